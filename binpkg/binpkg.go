@@ -0,0 +1,213 @@
+// Package binpkg implements reading and writing xbps binary packages.
+//
+// Binary packages are compressed tar archives. The package metadata, the
+// INSTALL and REMOVE scripts, props.plist and files.plist, is stored at the
+// beginning of the archive and followed by the package files.
+//
+// Packages compressed with zstd, gzip, bzip2 or not compressed at all can
+// be read, xz and lz4 compressed packages are not supported.
+package binpkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/klauspost/compress/zstd"
+	"howett.net/plist"
+)
+
+const (
+	// InstallEntry is the name of the INSTALL script inside the package
+	InstallEntry = "./INSTALL"
+	// RemoveEntry is the name of the REMOVE script inside the package
+	RemoveEntry = "./REMOVE"
+	// PropsEntry is the name of the package properties inside the package
+	PropsEntry = "./props.plist"
+	// FilesEntry is the name of the file list inside the package
+	FilesEntry = "./files.plist"
+)
+
+// ErrUnsupportedCompression is returned for packages with unsupported compression
+var ErrUnsupportedCompression = errors.New("unsupported package compression")
+
+var magics = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, "zstd"},
+	{[]byte{0x1f, 0x8b}, "gzip"},
+	{[]byte("BZh"), "bzip2"},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "xz"},
+	{[]byte{0x04, 0x22, 0x4d, 0x18}, "lz4"},
+}
+
+// Reader reads the entries of a binary package
+type Reader struct {
+	*tar.Reader
+	close func()
+}
+
+// NewReader detects the compression of the package and returns a Reader
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(6)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	comp := ""
+	for _, m := range magics {
+		if bytes.HasPrefix(head, m.magic) {
+			comp = m.name
+			break
+		}
+	}
+	rd := &Reader{close: func() {}}
+	switch comp {
+	case "zstd":
+		dec, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		rd.Reader, rd.close = tar.NewReader(dec), dec.Close
+	case "gzip":
+		dec, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		rd.Reader = tar.NewReader(dec)
+	case "bzip2":
+		rd.Reader = tar.NewReader(bzip2.NewReader(br))
+	case "":
+		rd.Reader = tar.NewReader(br)
+	default:
+		return nil, fmt.Errorf("%s: %w", comp, ErrUnsupportedCompression)
+	}
+	return rd, nil
+}
+
+// Close releases the resources of the decompressor
+func (r *Reader) Close() {
+	r.close()
+}
+
+// Metadata is the metadata of a binary package
+type Metadata struct {
+	// Props are the package properties
+	Props repo.Package
+	// Files is the list of files in the package
+	Files Files
+	// Install is the INSTALL script, nil if the package has none
+	Install []byte
+	// Remove is the REMOVE script, nil if the package has none
+	Remove []byte
+}
+
+// isMetadata returns true if the entry name is a metadata entry
+func isMetadata(name string) bool {
+	switch "./" + strings.TrimPrefix(name, "./") {
+	case InstallEntry, RemoveEntry, PropsEntry, FilesEntry:
+		return true
+	}
+	return false
+}
+
+// ReadMetadata reads the metadata from the beginning of the package.
+//
+// Reading stops at the first package file, the remaining
+// package is not read.
+func ReadMetadata(r io.Reader) (*Metadata, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	meta := &Metadata{}
+	var props bool
+	for {
+		hdr, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package: %w", err)
+		}
+		if !isMetadata(hdr.Name) {
+			break
+		}
+		buf, err := io.ReadAll(rd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package: %s: %w", hdr.Name, err)
+		}
+		switch "./" + strings.TrimPrefix(hdr.Name, "./") {
+		case InstallEntry:
+			meta.Install = buf
+		case RemoveEntry:
+			meta.Remove = buf
+		case PropsEntry:
+			if _, err := plist.Unmarshal(buf, &meta.Props); err != nil {
+				return nil, fmt.Errorf("failed to read package: %s: %w", hdr.Name, err)
+			}
+			props = true
+		case FilesEntry:
+			if _, err := plist.Unmarshal(buf, &meta.Files); err != nil {
+				return nil, fmt.Errorf("failed to read package: %s: %w", hdr.Name, err)
+			}
+		}
+	}
+	if !props {
+		return nil, errors.New("failed to read package: missing props.plist")
+	}
+	return meta, nil
+}
+
+// OpenMetadata reads the metadata of the package file
+func OpenMetadata(path string) (*Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	meta, err := ReadMetadata(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return meta, nil
+}
+
+// IndexPackage returns the repository index entry of the package file,
+// like xbps-rindex -a adds it to the repository index.
+func IndexPackage(path string) (*repo.Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	meta, err := ReadMetadata(io.TeeReader(f, h))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// hash the rest of the package
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	pkg := meta.Props
+	pkg.FilenameSHA256 = hex.EncodeToString(h.Sum(nil))
+	pkg.FilenameSize = size
+	return &pkg, nil
+}
@@ -0,0 +1,163 @@
+package binpkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+var testMeta = &Metadata{
+	Props: repo.Package{
+		PkgVer:       "foo-1.0_1",
+		Architecture: "x86_64",
+		ShortDesc:    "foo package",
+		RunDepends:   []string{"bar>=1.0_1"},
+	},
+	Files: Files{
+		Files: []Entry{{File: "/usr/bin/foo", SHA256: "abcd", Size: 3}},
+		Links: []Entry{{File: "/usr/bin/bar", Target: "/usr/bin/foo"}},
+		Dirs:  []Entry{{File: "/usr/share/foo"}},
+	},
+	Install: []byte("#!/bin/sh\n"),
+}
+
+func writePackage(t *testing.T, path string, meta *Metadata) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMetadata(meta); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("foo")
+	if err := w.WriteHeader(&tar.Header{Name: "./usr/bin/foo", Mode: 0o755, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo-1.0_1.x86_64.xbps")
+	writePackage(t, path, testMeta)
+	meta, err := OpenMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(meta, testMeta) {
+		t.Fatalf("expected %+v, got %+v", testMeta, meta)
+	}
+	expect := []string{"/usr/bin/foo", "/usr/bin/bar", "/usr/share/foo"}
+	if paths := meta.Files.Paths(); !reflect.DeepEqual(paths, expect) {
+		t.Fatalf("expected paths %v, got %v", expect, paths)
+	}
+}
+
+func TestIndexPackage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo-1.0_1.x86_64.xbps")
+	writePackage(t, path, testMeta)
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(buf)
+	pkg, err := IndexPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.FilenameSHA256 != hex.EncodeToString(hash[:]) || pkg.FilenameSize != int64(len(buf)) {
+		t.Fatalf("unexpected hash %q and size %d", pkg.FilenameSHA256, pkg.FilenameSize)
+	}
+	if pkg.PkgVer != "foo-1.0_1" {
+		t.Fatalf("unexpected pkgver %q", pkg.PkgVer)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	var tarbuf bytes.Buffer
+	tw := tar.NewWriter(&tarbuf)
+	props := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0"><dict><key>pkgver</key><string>foo-1.0_1</string></dict></plist>`)
+	tw.WriteHeader(&tar.Header{Name: "props.plist", Mode: 0o644, Size: int64(len(props))})
+	tw.Write(props)
+	tw.Close()
+
+	var gzbuf bytes.Buffer
+	gw := gzip.NewWriter(&gzbuf)
+	gw.Write(tarbuf.Bytes())
+	gw.Close()
+
+	for name, buf := range map[string][]byte{"none": tarbuf.Bytes(), "gzip": gzbuf.Bytes()} {
+		meta, err := ReadMetadata(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if meta.Props.PkgVer != "foo-1.0_1" {
+			t.Fatalf("%s: unexpected pkgver %q", name, meta.Props.PkgVer)
+		}
+	}
+	xz := []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00}
+	if _, err := ReadMetadata(bytes.NewReader(xz)); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Fatalf("expected ErrUnsupportedCompression, got %v", err)
+	}
+}
+
+const testInstall = `#!/bin/sh
+ACTION="$1"
+PKGNAME="$2"
+export system_accounts="_foo:123"
+export _foo_homedir="/var/lib/foo"
+export make_dirs="/var/lib/foo 0750 _foo _foo
+/var/log/foo 0755 _foo _foo"
+TRIGGERSDIR="./usr/libexec/xbps-triggers"
+case "${ACTION}" in
+pre)
+	${TRIGGERSDIR}/system-accounts run ${ACTION} ${PKGNAME} ${VERSION} ${UPDATE} ${CONF_FILE}
+	;;
+post)
+	${TRIGGERSDIR}/system-accounts run ${ACTION} ${PKGNAME} ${VERSION} ${UPDATE} ${CONF_FILE}
+	${TRIGGERSDIR}/mkdirs run ${ACTION} ${PKGNAME} ${VERSION} ${UPDATE} ${CONF_FILE}
+	;;
+esac
+`
+
+func TestParseTriggers(t *testing.T) {
+	tr := ParseTriggers([]byte(testInstall))
+	if !reflect.DeepEqual(tr.Names, []string{"system-accounts", "mkdirs"}) {
+		t.Fatalf("unexpected triggers %v", tr.Names)
+	}
+	expect := map[string][]string{"pre": {"system-accounts"}, "post": {"system-accounts", "mkdirs"}}
+	if !reflect.DeepEqual(tr.Actions, expect) {
+		t.Fatalf("unexpected actions %v", tr.Actions)
+	}
+	if tr.Vars["make_dirs"] != "/var/lib/foo 0750 _foo _foo\n/var/log/foo 0755 _foo _foo" {
+		t.Fatalf("unexpected multi-line variable %q", tr.Vars["make_dirs"])
+	}
+	if tr.Vars["_foo_homedir"] != "/var/lib/foo" {
+		t.Fatalf("unexpected variable %q", tr.Vars["_foo_homedir"])
+	}
+	tr = (&Metadata{Install: []byte("export triggers=\"register-shell pycompile\"\n")}).Triggers()
+	if !reflect.DeepEqual(tr.Names, []string{"register-shell", "pycompile"}) {
+		t.Fatalf("unexpected triggers %v", tr.Names)
+	}
+}
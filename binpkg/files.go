@@ -0,0 +1,30 @@
+package binpkg
+
+// Entry is a file, link or directory in the file list of a package
+type Entry struct {
+	File   string `plist:"file"`
+	SHA256 string `plist:"sha256,omitempty"`
+	Size   uint64 `plist:"size,omitempty"`
+	MTime  uint64 `plist:"mtime,omitempty"`
+	Target string `plist:"target,omitempty"`
+}
+
+// Files is the file list of a package, stored as files.plist
+// in binary packages and as .<pkgname>-files.plist in the package database.
+type Files struct {
+	Files     []Entry `plist:"files,omitempty"`
+	Links     []Entry `plist:"links,omitempty"`
+	ConfFiles []Entry `plist:"conf_files,omitempty"`
+	Dirs      []Entry `plist:"dirs,omitempty"`
+}
+
+// Paths returns the paths of all files, links, configuration files and directories
+func (f *Files) Paths() []string {
+	var res []string
+	for _, l := range [][]Entry{f.Files, f.Links, f.ConfFiles, f.Dirs} {
+		for _, e := range l {
+			res = append(res, e.File)
+		}
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+package binpkg
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Triggers are the xbps-triggers declared by a package script.
+//
+// xbps-src generates INSTALL and REMOVE scripts that export the trigger
+// variables and run the triggers from ${TRIGGERSDIR} for each action:
+//
+//	export register_shell="/bin/foosh"
+//	case "${ACTION}" in
+//	post)
+//		${TRIGGERSDIR}/register-shell run ${ACTION} ${PKGNAME} ...
+//		;;
+//	esac
+type Triggers struct {
+	// Names are the declared triggers in order of appearance
+	Names []string
+	// Actions maps script actions to the triggers they run
+	Actions map[string][]string
+	// Vars are the variables exported for the triggers
+	Vars map[string]string
+}
+
+var (
+	exportRe  = regexp.MustCompile(`^export\s+([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	actionRe  = regexp.MustCompile(`^([a-z|]+)\)$`)
+	triggerRe = regexp.MustCompile(`\$\{?TRIGGERSDIR\}?/([A-Za-z0-9_-]+)`)
+)
+
+// ParseTriggers parses the triggers declared by a package script
+func ParseTriggers(script []byte) *Triggers {
+	t := &Triggers{Actions: make(map[string][]string), Vars: make(map[string]string)}
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			t.Names = append(t.Names, name)
+		}
+	}
+	var actions []string
+	sc := bufio.NewScanner(bytes.NewReader(script))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := exportRe.FindStringSubmatch(line); m != nil {
+			value := m[2]
+			// quoted values may span multiple lines
+			if q := value[:min(1, len(value))]; q == `"` || q == `'` {
+				for !closed(value, q[0]) && sc.Scan() {
+					value += "\n" + sc.Text()
+				}
+				value = strings.TrimSuffix(strings.TrimPrefix(value, q), q)
+			}
+			t.Vars[m[1]] = value
+			if m[1] == "triggers" {
+				for _, name := range strings.Fields(value) {
+					add(name)
+				}
+			}
+			continue
+		}
+		if m := actionRe.FindStringSubmatch(line); m != nil {
+			actions = strings.Split(m[1], "|")
+			continue
+		}
+		if line == ";;" || line == "esac" {
+			actions = nil
+			continue
+		}
+		if m := triggerRe.FindStringSubmatch(line); m != nil && m[1] != "" {
+			add(m[1])
+			for _, action := range actions {
+				t.Actions[action] = append(t.Actions[action], m[1])
+			}
+		}
+	}
+	return t
+}
+
+// closed returns true if the quoted value ends with an unescaped quote
+func closed(value string, q byte) bool {
+	return len(value) > 1 && value[len(value)-1] == q && (len(value) < 3 || value[len(value)-2] != '\\')
+}
+
+// Triggers returns the triggers declared by the INSTALL script
+func (m *Metadata) Triggers() *Triggers {
+	return ParseTriggers(m.Install)
+}
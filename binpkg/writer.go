@@ -0,0 +1,79 @@
+package binpkg
+
+import (
+	"archive/tar"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"howett.net/plist"
+)
+
+// Writer writes zstd compressed binary packages.
+//
+// The metadata has to be written with WriteMetadata before
+// package files are written using the embedded tar.Writer.
+type Writer struct {
+	*tar.Writer
+	comp *zstd.Encoder
+	// ModTime is the modification time of the metadata entries
+	ModTime time.Time
+}
+
+// NewWriter returns a package writer
+func NewWriter(w io.Writer) (*Writer, error) {
+	comp, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{Writer: tar.NewWriter(comp), comp: comp, ModTime: time.Now()}, nil
+}
+
+func (w *Writer) writeEntry(name string, mode int64, buf []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(buf)),
+		ModTime: w.ModTime,
+	}
+	if err := w.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// WriteMetadata writes the package metadata in the order xbps-create writes it
+func (w *Writer) WriteMetadata(meta *Metadata) error {
+	if meta.Install != nil {
+		if err := w.writeEntry(InstallEntry, 0o755, meta.Install); err != nil {
+			return err
+		}
+	}
+	if meta.Remove != nil {
+		if err := w.writeEntry(RemoveEntry, 0o755, meta.Remove); err != nil {
+			return err
+		}
+	}
+	props, err := plist.MarshalIndent(&meta.Props, plist.XMLFormat, "\t")
+	if err != nil {
+		return err
+	}
+	if err := w.writeEntry(PropsEntry, 0o644, props); err != nil {
+		return err
+	}
+	files, err := plist.MarshalIndent(&meta.Files, plist.XMLFormat, "\t")
+	if err != nil {
+		return err
+	}
+	return w.writeEntry(FilesEntry, 0o644, files)
+}
+
+// Close finishes the archive and flushes the compressor,
+// it does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.Writer.Close(); err != nil {
+		return err
+	}
+	return w.comp.Close()
+}
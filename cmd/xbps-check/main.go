@@ -0,0 +1,61 @@
+// Command xbps-check verifies the installed packages like xbps-pkgdb -a.
+//
+// Usage:
+//
+//	xbps-check [-r rootdir] [-json] [pkgname...]
+//
+// The exit status is 1 if any problem was found.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Duncaen/go-xbps/pkgdb"
+)
+
+func main() {
+	rootdir := flag.String("r", "/", "root directory")
+	asJSON := flag.Bool("json", false, "print problems as JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-r rootdir] [-json] [pkgname...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	db, err := pkgdb.Open(*rootdir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var problems []pkgdb.Problem
+	if flag.NArg() == 0 {
+		problems = db.Check()
+	} else {
+		for _, name := range flag.Args() {
+			res, err := db.CheckPackage(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			problems = append(problems, res...)
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if problems == nil {
+			problems = []pkgdb.Problem{}
+		}
+		if err := enc.Encode(problems); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
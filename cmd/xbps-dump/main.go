@@ -0,0 +1,95 @@
+// Command xbps-dump converts repository data to and from JSON.
+//
+// Usage:
+//
+//	xbps-dump [-format json|yaml] [-a arch] repository
+//	xbps-dump -import [-o repodata] file.json
+//
+// Without -import the index, stage and public key of the repository are
+// written to standard output as JSON or YAML, see repo.SchemaVersion for the
+// schema. With -import a JSON file, or standard input for -, is converted
+// to repository data written to the file given with -o, which defaults to
+// <arch>-repodata. Importing YAML is not supported.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func main() {
+	format := flag.String("format", "json", "output format, json or yaml")
+	arch := flag.String("a", "", "repository architecture")
+	imp := flag.Bool("import", false, "convert JSON to repository data")
+	output := flag.String("o", "", "output file of -import")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-format json|yaml] [-a arch] repository\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s -import [-o repodata] file.json\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *imp {
+		if err := importJSON(flag.Arg(0), *output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	r, err := repo.Open(flag.Arg(0), *arch)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	case "yaml":
+		err = r.WriteYAML(os.Stdout)
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func importJSON(path, output string) error {
+	var buf []byte
+	var err error
+	if path == "-" {
+		buf, err = io.ReadAll(os.Stdin)
+	} else {
+		buf, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	var r repo.Repository
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if output == "" {
+		if r.Arch == "" {
+			return fmt.Errorf("%s: repository has no architecture, use -o", path)
+		}
+		output = r.Arch + "-repodata"
+	}
+	var out bytes.Buffer
+	if _, err := r.WriteTo(&out); err != nil {
+		return err
+	}
+	return repo.WriteFile(output, &out, time.Time{})
+}
@@ -0,0 +1,90 @@
+// Command xbps-lint checks the package metadata of repositories and binary
+// packages.
+//
+// Usage:
+//
+//	xbps-lint [-a arch] [-json] [-disable check,...] [-policy file] repodir|pkg.xbps...
+//
+// With -policy the licenses are checked against the JSON encoded license
+// policy in file. The exit status is 1 if any error was found.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/license"
+	"github.com/Duncaen/go-xbps/lint"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func main() {
+	arch := flag.String("a", "", "repository architecture")
+	asJSON := flag.Bool("json", false, "print findings as JSON")
+	disable := flag.String("disable", "", "comma separated checks to disable")
+	policy := flag.String("policy", "", "JSON license policy file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-a arch] [-json] [-disable check,...] [-policy file] repodir|pkg.xbps...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	l := lint.New()
+	if *disable != "" {
+		l.Disable(strings.Split(*disable, ",")...)
+	}
+	if *policy != "" {
+		buf, err := os.ReadFile(*policy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var p license.Policy
+		if err := json.Unmarshal(buf, &p); err != nil {
+			log.Fatalf("%s: %v", *policy, err)
+		}
+		l.Checks = append(l.Checks, lint.PolicyCheck(&p))
+	}
+	var findings []lint.Finding
+	for _, arg := range flag.Args() {
+		if strings.HasSuffix(arg, ".xbps") {
+			res, err := l.File(arg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			findings = append(findings, res...)
+			continue
+		}
+		r, err := repo.New(arg, *arch)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := r.Open(); err != nil {
+			log.Fatal(err)
+		}
+		findings = append(findings, l.Repository(r)...)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		if err := enc.Encode(findings); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+	}
+	if lint.Errors(findings) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,102 @@
+// Command xbps-locate finds the packages that own files.
+//
+// Usage:
+//
+//	xbps-locate -update [-r rootdir | -R repodir -a arch] index
+//	xbps-locate [-g | -e] index pattern
+//
+// With -update the index is built from the package database of rootdir or
+// the binary packages of the local repository repodir.
+// Otherwise the index is searched for the exact path, a glob with -g or a
+// regular expression with -e.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/Duncaen/go-xbps/fileindex"
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func main() {
+	update := flag.Bool("update", false, "build the index")
+	rootdir := flag.String("r", "/", "root directory of the package database")
+	repodir := flag.String("R", "", "local repository directory")
+	arch := flag.String("a", "", "repository architecture")
+	glob := flag.Bool("g", false, "match pattern as glob")
+	re := flag.Bool("e", false, "match pattern as regular expression")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s -update [-r rootdir | -R repodir -a arch] index\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [-g | -e] index pattern\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *update {
+		if flag.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		ix := fileindex.New()
+		if *repodir != "" {
+			r, err := repo.New(*repodir, *arch)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := r.Open(); err != nil {
+				log.Fatal(err)
+			}
+			if err := ix.AddRepository(r, *repodir); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			db, err := pkgdb.Open(*rootdir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := ix.AddPkgDB(db); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := ix.Save(flag.Arg(0)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	ix, err := fileindex.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	pattern := flag.Arg(1)
+	var owners []fileindex.Owner
+	switch {
+	case *glob:
+		if owners, err = ix.Glob(pattern); err != nil {
+			log.Fatal(err)
+		}
+	case *re:
+		expr, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Fatal(err)
+		}
+		owners = ix.Regexp(expr)
+	default:
+		owners = ix.Lookup(pattern)
+	}
+	for _, o := range owners {
+		fmt.Println(o)
+	}
+	if len(owners) == 0 {
+		os.Exit(1)
+	}
+}
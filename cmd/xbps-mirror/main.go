@@ -0,0 +1,67 @@
+// Command xbps-mirror mirrors a repository to a local directory.
+//
+// Usage:
+//
+//	xbps-mirror [-a arch]... [-j jobs] [-keys dir] source dir
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/repo/mirror"
+)
+
+type archFlag []string
+
+func (a *archFlag) String() string { return strings.Join(*a, ",") }
+
+func (a *archFlag) Set(s string) error {
+	*a = append(*a, s)
+	return nil
+}
+
+func main() {
+	var archs archFlag
+	flag.Var(&archs, "a", "architecture to mirror, can be repeated")
+	jobs := flag.Int("j", 4, "number of concurrent downloads")
+	keys := flag.String("keys", "", "directory of trusted repository keys")
+	verbose := flag.Bool("v", false, "print downloaded and deleted files")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-a arch]... [-j jobs] [-keys dir] [-v] source dir\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 || len(archs) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	m, err := mirror.New(flag.Arg(0), flag.Arg(1), archs...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m.Jobs = *jobs
+	if *keys != "" {
+		m.Keys = &repo.KeyStore{Dir: *keys}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	res, err := m.Sync(ctx)
+	if res != nil && *verbose {
+		for _, path := range res.Downloaded {
+			fmt.Println("downloaded", path)
+		}
+		for _, path := range res.Deleted {
+			fmt.Println("deleted", path)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
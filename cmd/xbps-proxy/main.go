@@ -0,0 +1,55 @@
+// Command xbps-proxy is a caching proxy for a remote xbps repository.
+//
+// Usage:
+//
+//	xbps-proxy [-l addr] [-ttl duration] [-keys dir] [-a arch]... upstream cachedir
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/repo/proxy"
+)
+
+type archFlag []string
+
+func (a *archFlag) String() string { return strings.Join(*a, ",") }
+
+func (a *archFlag) Set(s string) error {
+	*a = append(*a, s)
+	return nil
+}
+
+func main() {
+	var archs archFlag
+	flag.Var(&archs, "a", "architecture noarch packages are looked up in, can be repeated")
+	addr := flag.String("l", ":8080", "listen address")
+	ttl := flag.Duration("ttl", proxy.DefaultTTL, "time after which repository data is revalidated")
+	keys := flag.String("keys", "", "directory of trusted repository keys")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-l addr] [-ttl duration] [-keys dir] [-a arch]... upstream cachedir\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	p, err := proxy.New(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	p.TTL = *ttl
+	p.Archs = archs
+	if *keys != "" {
+		p.Keys = &repo.KeyStore{Dir: *keys}
+	}
+	log.Printf("proxying %s on %s", flag.Arg(0), *addr)
+	log.Fatal(http.ListenAndServe(*addr, p))
+}
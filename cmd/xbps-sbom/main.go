@@ -0,0 +1,75 @@
+// Command xbps-sbom writes a software bill of materials of the installed
+// packages or a local repository.
+//
+// Usage:
+//
+//	xbps-sbom [-format spdx|cyclonedx] [-name name] [-r rootdir | -R repodir -a arch]
+//
+// The creation time is taken from SOURCE_DATE_EPOCH if it is set.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/sbom"
+)
+
+func main() {
+	format := flag.String("format", "spdx", "output format, spdx or cyclonedx")
+	name := flag.String("name", "", "document name")
+	rootdir := flag.String("r", "/", "root directory of the package database")
+	repodir := flag.String("R", "", "local repository directory")
+	arch := flag.String("a", "", "repository architecture")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-format spdx|cyclonedx] [-name name] [-r rootdir | -R repodir -a arch]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var s *sbom.SBOM
+	if *repodir != "" {
+		r, err := repo.New(*repodir, *arch)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := r.Open(); err != nil {
+			log.Fatal(err)
+		}
+		s = sbom.FromRepository(*name, r)
+	} else {
+		db, err := pkgdb.Open(*rootdir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s = sbom.FromPkgDB(*name, db)
+	}
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			log.Fatalf("SOURCE_DATE_EPOCH: %v", err)
+		}
+		s.Created = time.Unix(sec, 0).UTC()
+	}
+	var err error
+	switch *format {
+	case "spdx":
+		err = s.WriteSPDX(os.Stdout)
+	case "cyclonedx":
+		err = s.WriteCycloneDX(os.Stdout)
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
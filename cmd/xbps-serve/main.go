@@ -0,0 +1,34 @@
+// Command xbps-serve serves a directory as xbps repository over HTTP.
+//
+// Usage:
+//
+//	xbps-serve [-l addr] [-regenerate] dir
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/Duncaen/go-xbps/repo/server"
+)
+
+func main() {
+	addr := flag.String("l", ":8080", "listen address")
+	regenerate := flag.Bool("regenerate", false, "regenerate repository data when packages change")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-l addr] [-regenerate] dir\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	s := server.New(flag.Arg(0))
+	s.Regenerate = *regenerate
+	log.Printf("serving %s on %s", s.Dir, *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
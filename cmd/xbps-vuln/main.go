@@ -0,0 +1,108 @@
+// Command xbps-vuln matches packages against offline vulnerability
+// advisories.
+//
+// Usage:
+//
+//	xbps-vuln [-json] [-a arch] [-u repodir]... [-r rootdir | -R repodir] advisories
+//
+// The advisories are a directory of OSV JSON files, an OSV JSON file or a
+// simple advisory list. The installed packages of rootdir or the packages
+// of the local repository repodir are checked, fixed packages are searched
+// in the repositories given with -u. The exit status is 1 if any package
+// is affected.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/vuln"
+)
+
+type repoFlag []string
+
+func (r *repoFlag) String() string { return strings.Join(*r, ",") }
+
+func (r *repoFlag) Set(s string) error {
+	*r = append(*r, s)
+	return nil
+}
+
+func openRepo(dir, arch string) *repo.Repository {
+	r, err := repo.New(dir, arch)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := r.Open(); err != nil {
+		log.Fatal(err)
+	}
+	return r
+}
+
+func main() {
+	var updates repoFlag
+	asJSON := flag.Bool("json", false, "print matches as JSON")
+	arch := flag.String("a", "", "repository architecture")
+	rootdir := flag.String("r", "/", "root directory of the package database")
+	repodir := flag.String("R", "", "check the local repository instead of installed packages")
+	flag.Var(&updates, "u", "repository searched for fixed packages, can be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-json] [-a arch] [-u repodir]... [-r rootdir | -R repodir] advisories\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	advs, err := vuln.Load(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	m := vuln.New(advs)
+	for _, dir := range updates {
+		m.Repos = append(m.Repos, openRepo(dir, *arch))
+	}
+	var matches []vuln.Match
+	if *repodir != "" {
+		matches = m.Repository(openRepo(*repodir, *arch))
+	} else {
+		db, err := pkgdb.Open(*rootdir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		matches = m.PkgDB(db)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if matches == nil {
+			matches = []vuln.Match{}
+		}
+		if err := enc.Encode(matches); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, v := range matches {
+			line := fmt.Sprintf("%s: %s", v.PkgVer, v.ID)
+			if v.Severity != "" {
+				line += " " + v.Severity
+			}
+			if v.Available != "" {
+				line += " (fixed in " + v.Available + ")"
+			} else if len(v.Fixed) > 0 {
+				line += " (fixed in " + strings.Join(v.Fixed, ", ") + ", not available)"
+			}
+			fmt.Println(line)
+		}
+	}
+	if len(matches) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrKeyNotFound is returned if the agent does not hold the key
+var ErrKeyNotFound = errors.New("key not found in agent")
+
+// AgentSigner signs .sig2 signatures with an RSA key held by an ssh-agent.
+//
+// The agent protocol signs messages instead of digests, the agent hashes
+// the message itself. This only matches the .sig2 format, which signs the
+// sha256 hash of the file with the correct algorithm identifier, legacy
+// .sig signatures with the SHA1/SHA256 prefix quirk can not be created
+// with an agent. The whole file is sent to the agent, OpenSSHs agent
+// rejects messages larger than 256KiB, which limits agent signing to
+// small files like repository data.
+//
+// AgentSigner does not implement crypto.Signer because it can not sign
+// precomputed digests.
+type AgentSigner struct {
+	agent agent.ExtendedAgent
+	key   ssh.PublicKey
+	pub   *rsa.PublicKey
+}
+
+// DialAgent connects to the ssh-agent listening on the unix socket, like
+// the path in SSH_AUTH_SOCK.
+func DialAgent(socket string) (agent.ExtendedAgent, net.Conn, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not connect to agent: %w", err)
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+// NewAgentSigner returns a signer for the public key held by the agent
+func NewAgentSigner(a agent.ExtendedAgent, pub *rsa.PublicKey) (*AgentSigner, error) {
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := a.List()
+	if err != nil {
+		return nil, fmt.Errorf("could not list agent keys: %w", err)
+	}
+	for _, k := range keys {
+		if bytes.Equal(k.Marshal(), key.Marshal()) {
+			return &AgentSigner{agent: a, key: key, pub: pub}, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Public returns the public key
+func (s *AgentSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// SignSig2 signs the message in the format of xbps .sig2 files
+func (s *AgentSigner) SignSig2(msg []byte) ([]byte, error) {
+	sig, err := s.agent.SignWithFlags(s.key, msg, agent.SignatureFlagRsaSha256)
+	if err != nil {
+		return nil, fmt.Errorf("agent could not sign: %w", err)
+	}
+	if sig.Format != ssh.KeyAlgoRSASHA256 {
+		return nil, fmt.Errorf("agent returned unexpected signature format %q", sig.Format)
+	}
+	// do not trust the agent to have used the right key and hash
+	hashed := sha256.Sum256(msg)
+	if err := VerifySig2(s.pub, hashed[:], sig.Blob); err != nil {
+		return nil, fmt.Errorf("agent returned invalid signature: %w", err)
+	}
+	return sig.Blob, nil
+}
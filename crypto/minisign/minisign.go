@@ -0,0 +1,430 @@
+// Package minisign implements Ed25519 signatures compatible with minisign.
+//
+// This is an opt-in alternative to the RSA signatures of xbps, signatures
+// are stored next to the signed file with the .minisig extension.
+//
+// Public keys are the base64 encoded algorithm "Ed", the 8 byte key id and
+// the 32 byte Ed25519 public key. Signatures consist of four lines:
+//
+//	untrusted comment: <comment>
+//	base64(algorithm || key id || ed25519 signature)
+//	trusted comment: <comment>
+//	base64(ed25519 signature of the signature and the trusted comment)
+//
+// New signatures use the "ED" algorithm, which signs the blake2b-512 hash
+// of the file, legacy "Ed" signatures of the whole file are verified too.
+//
+// Specification:
+//
+// https://jedisct1.github.io/minisign/
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Ext is the file extension of detached signatures
+const Ext = ".minisig"
+
+var (
+	// ErrMalformed is returned for keys and signatures that do not parse
+	ErrMalformed = errors.New("malformed minisign data")
+	// ErrKeyMismatch is returned if a signature was made with another key
+	ErrKeyMismatch = errors.New("signature key id does not match public key")
+	// ErrInvalidSignature is returned if signature verification fails
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrPassword is returned if the secret key could not be decrypted
+	ErrPassword = errors.New("wrong password or corrupt secret key")
+)
+
+var (
+	algEd     = [2]byte{'E', 'd'}
+	algHashed = [2]byte{'E', 'D'}
+	kdfScrypt = [2]byte{'S', 'c'}
+	kdfNone   = [2]byte{0, 0}
+	chkBlake2 = [2]byte{'B', '2'}
+)
+
+const (
+	untrustedPrefix = "untrusted comment: "
+	trustedPrefix   = "trusted comment: "
+
+	// scrypt parameters used by minisign to encrypt secret keys
+	scryptOpsLimit = 33554432
+	scryptMemLimit = 1073741824
+)
+
+// PublicKey is a minisign Ed25519 public key
+type PublicKey struct {
+	ID  [8]byte
+	Key ed25519.PublicKey
+}
+
+// ParsePublicKey parses a public key file or the base64 encoded key
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	lines := splitLines(data)
+	if len(lines) > 0 && strings.HasPrefix(lines[0], untrustedPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("public key: %w", ErrMalformed)
+	}
+	buf, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(buf) != 2+8+ed25519.PublicKeySize || !bytes.Equal(buf[:2], algEd[:]) {
+		return nil, fmt.Errorf("public key: %w", ErrMalformed)
+	}
+	k := &PublicKey{Key: ed25519.PublicKey(buf[10:])}
+	copy(k.ID[:], buf[2:10])
+	return k, nil
+}
+
+// KeyID returns the key id in the hexadecimal format minisign displays
+func (k *PublicKey) KeyID() string {
+	return keyID(k.ID)
+}
+
+// String returns the base64 encoded public key
+func (k *PublicKey) String() string {
+	buf := make([]byte, 0, 2+8+ed25519.PublicKeySize)
+	buf = append(buf, algEd[:]...)
+	buf = append(buf, k.ID[:]...)
+	buf = append(buf, k.Key...)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// MarshalText returns the public key in the minisign public key file format
+func (k *PublicKey) MarshalText() ([]byte, error) {
+	if len(k.Key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key: %w", ErrMalformed)
+	}
+	return []byte(fmt.Sprintf("%sminisign public key %s\n%s\n", untrustedPrefix, k.KeyID(), k)), nil
+}
+
+// PrivateKey is a minisign Ed25519 secret key
+type PrivateKey struct {
+	ID  [8]byte
+	Key ed25519.PrivateKey
+}
+
+// GenerateKey generates a new key with a random key id
+func GenerateKey(rd io.Reader) (*PrivateKey, error) {
+	if rd == nil {
+		rd = rand.Reader
+	}
+	k := &PrivateKey{}
+	if _, err := io.ReadFull(rd, k.ID[:]); err != nil {
+		return nil, err
+	}
+	_, priv, err := ed25519.GenerateKey(rd)
+	if err != nil {
+		return nil, err
+	}
+	k.Key = priv
+	return k, nil
+}
+
+// Public returns the public key
+func (k *PrivateKey) Public() *PublicKey {
+	return &PublicKey{ID: k.ID, Key: k.Key.Public().(ed25519.PublicKey)}
+}
+
+// secret key layout after the base64 decoding
+const (
+	skAlg      = 0
+	skKdf      = 2
+	skChk      = 4
+	skSalt     = 6
+	skOps      = 38
+	skMem      = 46
+	skKeynum   = 54
+	skKeynumSz = 8 + ed25519.PrivateKeySize + blake2b.Size256
+	skSize     = skKeynum + skKeynumSz
+)
+
+// ParsePrivateKey parses a minisign secret key file, the password is
+// ignored for unencrypted keys.
+func ParsePrivateKey(data, password []byte) (*PrivateKey, error) {
+	lines := splitLines(data)
+	if len(lines) > 0 && strings.HasPrefix(lines[0], untrustedPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("secret key: %w", ErrMalformed)
+	}
+	buf, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(buf) != skSize || !bytes.Equal(buf[skAlg:skKdf], algEd[:]) ||
+		!bytes.Equal(buf[skChk:skSalt], chkBlake2[:]) {
+		return nil, fmt.Errorf("secret key: %w", ErrMalformed)
+	}
+	keynum := buf[skKeynum:]
+	switch {
+	case bytes.Equal(buf[skKdf:skChk], kdfScrypt[:]):
+		stream, err := kdf(password, buf[skSalt:skOps],
+			binary.LittleEndian.Uint64(buf[skOps:skMem]), binary.LittleEndian.Uint64(buf[skMem:skKeynum]))
+		if err != nil {
+			return nil, fmt.Errorf("secret key: %w", err)
+		}
+		subtle.XORBytes(keynum, keynum, stream)
+	case bytes.Equal(buf[skKdf:skChk], kdfNone[:]):
+	default:
+		return nil, fmt.Errorf("secret key: unsupported key derivation: %w", ErrMalformed)
+	}
+	k := &PrivateKey{Key: ed25519.PrivateKey(keynum[8 : 8+ed25519.PrivateKeySize])}
+	copy(k.ID[:], keynum[:8])
+	chk := k.checksum()
+	if subtle.ConstantTimeCompare(chk[:], keynum[8+ed25519.PrivateKeySize:]) != 1 {
+		return nil, ErrPassword
+	}
+	return k, nil
+}
+
+// Marshal returns the secret key in the minisign secret key file format,
+// the key is encrypted if password is not nil.
+func (k *PrivateKey) Marshal(password []byte) ([]byte, error) {
+	if len(k.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("secret key: %w", ErrMalformed)
+	}
+	buf := make([]byte, skSize)
+	copy(buf[skAlg:], algEd[:])
+	copy(buf[skChk:], chkBlake2[:])
+	keynum := buf[skKeynum:]
+	copy(keynum, k.ID[:])
+	copy(keynum[8:], k.Key)
+	chk := k.checksum()
+	copy(keynum[8+ed25519.PrivateKeySize:], chk[:])
+	comment := "minisign secret key"
+	if password != nil {
+		comment = "minisign encrypted secret key"
+		copy(buf[skKdf:], kdfScrypt[:])
+		if _, err := io.ReadFull(rand.Reader, buf[skSalt:skOps]); err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint64(buf[skOps:], scryptOpsLimit)
+		binary.LittleEndian.PutUint64(buf[skMem:], scryptMemLimit)
+		stream, err := kdf(password, buf[skSalt:skOps], scryptOpsLimit, scryptMemLimit)
+		if err != nil {
+			return nil, err
+		}
+		subtle.XORBytes(keynum, keynum, stream)
+	}
+	return []byte(fmt.Sprintf("%s%s\n%s\n", untrustedPrefix, comment, base64.StdEncoding.EncodeToString(buf))), nil
+}
+
+// checksum returns the blake2b-256 checksum of the secret key
+func (k *PrivateKey) checksum() [blake2b.Size256]byte {
+	var buf []byte
+	buf = append(buf, algEd[:]...)
+	buf = append(buf, k.ID[:]...)
+	buf = append(buf, k.Key...)
+	return blake2b.Sum256(buf)
+}
+
+// kdf derives the key stream of encrypted secret keys, the memory limit
+// is capped at the minisign default to bound the memory of untrusted keys.
+func kdf(password, salt []byte, opslimit, memlimit uint64) ([]byte, error) {
+	if memlimit > scryptMemLimit {
+		return nil, fmt.Errorf("scrypt memory limit %d exceeds %d: %w", memlimit, uint64(scryptMemLimit), ErrMalformed)
+	}
+	logN, r, p := scryptParams(opslimit, memlimit)
+	if logN > 30 || p == 0 {
+		return nil, fmt.Errorf("unsupported scrypt parameters: %w", ErrMalformed)
+	}
+	return scrypt.Key(password, salt, 1<<logN, int(r), int(p), skKeynumSz)
+}
+
+// scryptParams returns the scrypt parameters for the ops and mem limits
+// like libsodiums crypto_pwhash_scryptsalsa208sha256.
+func scryptParams(opslimit, memlimit uint64) (logN, r, p uint64) {
+	if opslimit < 32768 {
+		opslimit = 32768
+	}
+	r = 8
+	maxN := memlimit / (r * 128)
+	if opslimit < memlimit/32 {
+		maxN = opslimit / (r * 4)
+	}
+	for logN = 1; logN < 63; logN++ {
+		if uint64(1)<<logN > maxN/2 {
+			break
+		}
+	}
+	if opslimit < memlimit/32 {
+		return logN, r, 1
+	}
+	maxrp := (opslimit / 4) / (uint64(1) << logN)
+	if maxrp > 0x3fffffff {
+		maxrp = 0x3fffffff
+	}
+	return logN, r, maxrp / r
+}
+
+// Signature is a parsed minisign signature
+type Signature struct {
+	// Prehashed is true for signatures of the blake2b-512 hash of the file
+	Prehashed bool
+	// KeyID is the id of the key the signature was made with
+	KeyID [8]byte
+	// Signature is the Ed25519 signature of the file or its hash
+	Signature []byte
+	// UntrustedComment is the comment that is not signed
+	UntrustedComment string
+	// TrustedComment is the signed comment
+	TrustedComment string
+	// GlobalSignature is the Ed25519 signature of the signature and
+	// trusted comment
+	GlobalSignature []byte
+}
+
+// ParseSignature parses a minisign signature file
+func ParseSignature(data []byte) (*Signature, error) {
+	lines := splitLines(data)
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], untrustedPrefix) || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return nil, fmt.Errorf("signature: %w", ErrMalformed)
+	}
+	buf, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(buf) != 2+8+ed25519.SignatureSize {
+		return nil, fmt.Errorf("signature: %w", ErrMalformed)
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("signature: %w", ErrMalformed)
+	}
+	s := &Signature{
+		Signature:        buf[10:],
+		UntrustedComment: strings.TrimPrefix(lines[0], untrustedPrefix),
+		TrustedComment:   strings.TrimPrefix(lines[2], trustedPrefix),
+		GlobalSignature:  global,
+	}
+	switch {
+	case bytes.Equal(buf[:2], algHashed[:]):
+		s.Prehashed = true
+	case bytes.Equal(buf[:2], algEd[:]):
+	default:
+		return nil, fmt.Errorf("signature: unsupported algorithm: %w", ErrMalformed)
+	}
+	copy(s.KeyID[:], buf[2:10])
+	return s, nil
+}
+
+// MarshalText returns the signature in the minisign signature file format
+func (s *Signature) MarshalText() ([]byte, error) {
+	if len(s.Signature) != ed25519.SignatureSize || len(s.GlobalSignature) != ed25519.SignatureSize ||
+		strings.ContainsAny(s.UntrustedComment, "\r\n") || strings.ContainsAny(s.TrustedComment, "\r\n") {
+		return nil, fmt.Errorf("signature: %w", ErrMalformed)
+	}
+	alg := algEd
+	if s.Prehashed {
+		alg = algHashed
+	}
+	buf := make([]byte, 0, 2+8+ed25519.SignatureSize)
+	buf = append(buf, alg[:]...)
+	buf = append(buf, s.KeyID[:]...)
+	buf = append(buf, s.Signature...)
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s%s\n", untrustedPrefix, s.UntrustedComment)
+	fmt.Fprintf(&out, "%s\n", base64.StdEncoding.EncodeToString(buf))
+	fmt.Fprintf(&out, "%s%s\n", trustedPrefix, s.TrustedComment)
+	fmt.Fprintf(&out, "%s\n", base64.StdEncoding.EncodeToString(s.GlobalSignature))
+	return out.Bytes(), nil
+}
+
+// Verify verifies the signature of the message, including the trusted comment
+func (s *Signature) Verify(pub *PublicKey, msg io.Reader) error {
+	if s.KeyID != pub.ID {
+		return fmt.Errorf("key id %s instead of %s: %w", keyID(s.KeyID), pub.KeyID(), ErrKeyMismatch)
+	}
+	if len(pub.Key) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key: %w", ErrMalformed)
+	}
+	m, err := message(msg, s.Prehashed)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub.Key, m, s.Signature) {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(pub.Key, s.global(), s.GlobalSignature) {
+		return fmt.Errorf("trusted comment: %w", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// global returns the message of the global signature
+func (s *Signature) global() []byte {
+	return append(append([]byte(nil), s.Signature...), s.TrustedComment...)
+}
+
+// Verify verifies the minisign signature file sig of the message
+func Verify(pub *PublicKey, msg io.Reader, sig []byte) error {
+	s, err := ParseSignature(sig)
+	if err != nil {
+		return err
+	}
+	return s.Verify(pub, msg)
+}
+
+// Sign returns a prehashed signature of the message with the trusted comment
+func Sign(priv *PrivateKey, msg io.Reader, trustedComment string) (*Signature, error) {
+	if len(priv.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("secret key: %w", ErrMalformed)
+	}
+	if strings.ContainsAny(trustedComment, "\r\n") {
+		return nil, fmt.Errorf("trusted comment contains a newline: %w", ErrMalformed)
+	}
+	m, err := message(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	s := &Signature{
+		Prehashed:        true,
+		KeyID:            priv.ID,
+		Signature:        ed25519.Sign(priv.Key, m),
+		UntrustedComment: fmt.Sprintf("signature from minisign secret key %s", keyID(priv.ID)),
+		TrustedComment:   trustedComment,
+	}
+	s.GlobalSignature = ed25519.Sign(priv.Key, s.global())
+	return s, nil
+}
+
+// message returns the signed message, the blake2b-512 hash if prehashed
+func message(rd io.Reader, prehashed bool) ([]byte, error) {
+	if !prehashed {
+		return io.ReadAll(rd)
+	}
+	h, err := blake2b.New512(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(h, rd); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// keyID formats the little endian key id like minisign
+func keyID(id [8]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(id[:]))
+}
+
+// splitLines returns the non empty lines of data
+func splitLines(data []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,251 @@
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.Public()
+	msg := []byte("foo-1.0_1.x86_64.xbps")
+	sig, err := Sign(priv, bytes.NewReader(msg), "timestamp:0\tfile:foo-1.0_1.x86_64.xbps\thashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := sig.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(pub, bytes.NewReader(msg), buf); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSignature(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Prehashed || parsed.TrustedComment != sig.TrustedComment || parsed.KeyID != pub.ID {
+		t.Fatalf("unexpected signature %+v", parsed)
+	}
+
+	if err := Verify(pub, strings.NewReader("bar"), buf); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	parsed.TrustedComment = "timestamp:1"
+	if err := parsed.Verify(pub, bytes.NewReader(msg)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for modified trusted comment, got %v", err)
+	}
+	other, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(other.Public(), bytes.NewReader(msg), buf); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err := Sign(priv, bytes.NewReader(msg), "foo\nbar"); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("expected ErrMalformed for multi line comment, got %v", err)
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("legacy")
+	sig := &Signature{
+		KeyID:            priv.ID,
+		Signature:        ed25519.Sign(priv.Key, msg),
+		UntrustedComment: "signature from minisign secret key",
+		TrustedComment:   "timestamp:0",
+	}
+	sig.GlobalSignature = ed25519.Sign(priv.Key, sig.global())
+	buf, err := sig.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, err := ParseSignature(buf); err != nil || parsed.Prehashed {
+		t.Fatalf("expected Ed signature, got %+v, %v", parsed, err)
+	}
+	if err := Verify(priv.Public(), bytes.NewReader(msg), buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.Public()
+	buf, err := pub.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(buf), "untrusted comment: minisign public key "+pub.KeyID()+"\nRW") {
+		t.Fatalf("unexpected public key file %q", buf)
+	}
+	for _, data := range [][]byte{buf, []byte(pub.String())} {
+		parsed, err := ParsePublicKey(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.ID != pub.ID || !parsed.Key.Equal(pub.Key) {
+			t.Fatalf("parsed key %+v does not match %+v", parsed, pub)
+		}
+	}
+	if _, err := ParsePublicKey([]byte("RWQ=")); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("expected ErrMalformed, got %v", err)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	id := [8]byte{0xef, 0xcd, 0xab, 0x89, 0x67, 0x45, 0x23, 0x01}
+	if s := keyID(id); s != "0123456789ABCDEF" {
+		t.Fatalf("unexpected key id %s", s)
+	}
+}
+
+func TestPrivateKey(t *testing.T) {
+	priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := priv.Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePrivateKey(buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ID != priv.ID || !parsed.Key.Equal(priv.Key) {
+		t.Fatal("parsed secret key does not match")
+	}
+
+	// encrypt with cheap scrypt parameters, the defaults need 1GiB of memory
+	raw, err := base64.StdEncoding.DecodeString(strings.Split(string(buf), "\n")[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(raw[skKdf:], kdfScrypt[:])
+	copy(raw[skSalt:skOps], bytes.Repeat([]byte{1}, 32))
+	binary.LittleEndian.PutUint64(raw[skOps:], 32768)
+	binary.LittleEndian.PutUint64(raw[skMem:], 1<<20)
+	stream, err := kdf([]byte("secret"), raw[skSalt:skOps], 32768, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtle.XORBytes(raw[skKeynum:], raw[skKeynum:], stream)
+	enc := []byte("untrusted comment: minisign encrypted secret key\n" + base64.StdEncoding.EncodeToString(raw) + "\n")
+	parsed, err = ParsePrivateKey(enc, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Key.Equal(priv.Key) {
+		t.Fatal("decrypted secret key does not match")
+	}
+	if _, err := ParsePrivateKey(enc, []byte("wrong")); !errors.Is(err, ErrPassword) {
+		t.Fatalf("expected ErrPassword, got %v", err)
+	}
+
+	// memory limits above the minisign default are refused
+	binary.LittleEndian.PutUint64(raw[skMem:], scryptMemLimit+1)
+	enc = []byte("untrusted comment: minisign encrypted secret key\n" + base64.StdEncoding.EncodeToString(raw) + "\n")
+	if _, err := ParsePrivateKey(enc, []byte("secret")); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("expected ErrMalformed, got %v", err)
+	}
+}
+
+func TestScryptParams(t *testing.T) {
+	// minisign encrypts secret keys with N=2^20, r=8, p=1
+	if logN, r, p := scryptParams(scryptOpsLimit, scryptMemLimit); logN != 20 || r != 8 || p != 1 {
+		t.Fatalf("unexpected scrypt parameters N=2^%d r=%d p=%d", logN, r, p)
+	}
+}
+
+// vectors created with the minisign tool, from the test suites of
+// github.com/jedisct1/go-minisign and aead.dev/minisign
+const (
+	testPub       = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+	testSigLegacy = "untrusted comment: signature from minisign secret key\nRWQf6LRCGA9i59SLOFxz6NxvASXDJeRtuZykwQepbDEGt87ig1BNpWaVWuNrm73YiIiJbq71Wi+dP9eKL8OC351vwIasSSbXxwA=\ntrusted comment: timestamp:1635442742\tfile:test\n0YteLgV960ia80vnA/fHbvkyjl/IoP/HNOCaZfrF0CdhAlp7ok+Tpkya+VpWPX5C/Is3q8a/kEDSY7fBmmgJCg==\n"
+	testSigHashed = "untrusted comment: signature from minisign secret key\nRUQf6LRCGA9i559r3g7V1qNyJDApGip8MfqcadIgT9CuhV3EMhHoN1mGTkUidF/z7SrlQgXdy8ofjb7bNJJylDOocrCo8KLzZwo=\ntrusted comment: timestamp:1635443258\tfile:test\thashed\n/cj37GK60vryibFn+ftOgbCvW9NKhKYgjVpFFQUcWPAnjO23wrvVDTt7cloNC06maoBli9q6qwZDXXoaxweICQ==\n"
+
+	testKeyPub  = "untrusted comment: minisign public key C373193807678450\nRWRQhGcHOBlzw4CoKyugkk4ioDfoxlXxC9LBx+VNhJ3w9w+cAxgvPsuo\n"
+	testKey     = "untrusted comment: minisign encrypted secret key\nRWRTY0Iytaz5znJmUO5kBt5xVkvpBl+29A7pZH86phD4h8vD3V8AAAACAAAAAAAAAEAAAAAA9vH9EcS6NdXNIEGhYGoqG1CiL4aptyJreJ4IfuT4+1h+OgVaY/vi0HsbCP0Y6n/wcy0AN0wOXmVDPP33jZqv82YCj2fH+/6MRuAfzNQYoLvc3sH/8bIwqdfpKIjDRZhvqRf063RFYoI=\n"
+	testKeyPass = "correct horse battery staple"
+	testKeyMsg  = "Hello World!\n"
+	testKeySig  = "untrusted comment: signature from minisign secret key\nRWRQhGcHOBlzwxrJCyuC+rJfHSfyRKRxkuwa3JJ0bWEs7RHjL1OUmqnTr+V1B9JzFuJIH/ybR2Eus9oEZKt9RbitpF/L4D3+5wg=\ntrusted comment: timestamp:1614549543\tfile:message.txt\nP/722+ynQ+tIy0qadFHwLx5MsyNz/jDKJkDWQj4dDD2OKnVte8m/M14mwPE/1NMwzShPMSBhMXqZGdbe+UZjDg==\n"
+)
+
+func TestVectors(t *testing.T) {
+	pub, err := ParsePublicKey([]byte(testPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		sig       string
+		prehashed bool
+	}{
+		{testSigLegacy, false},
+		{testSigHashed, true},
+	} {
+		sig, err := ParseSignature([]byte(tt.sig))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.Prehashed != tt.prehashed {
+			t.Errorf("expected prehashed %v, got %v", tt.prehashed, sig.Prehashed)
+		}
+		if err := sig.Verify(pub, strings.NewReader("test")); err != nil {
+			t.Errorf("%s: %v", sig.TrustedComment, err)
+		}
+		if buf, err := sig.MarshalText(); err != nil || string(buf) != tt.sig {
+			t.Errorf("marshaled signature does not match: %q, %v", buf, err)
+		}
+	}
+
+	pub, err = ParsePublicKey([]byte(testKeyPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.KeyID() != "C373193807678450" {
+		t.Fatalf("unexpected key id %s", pub.KeyID())
+	}
+	if buf, err := pub.MarshalText(); err != nil || string(buf) != testKeyPub {
+		t.Fatalf("marshaled public key does not match: %q, %v", buf, err)
+	}
+	if err := Verify(pub, strings.NewReader(testKeyMsg), []byte(testKeySig)); err != nil {
+		t.Fatal(err)
+	}
+	if testing.Short() {
+		t.Skip("decrypting the secret key needs 1GiB of memory")
+	}
+	priv, err := ParsePrivateKey([]byte(testKey), []byte(testKeyPass))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priv.ID != pub.ID || !priv.Public().Key.Equal(pub.Key) {
+		t.Fatal("secret key does not match public key")
+	}
+	// Ed25519 signatures are deterministic, the legacy signature is recreated
+	sig := &Signature{
+		KeyID:            priv.ID,
+		Signature:        ed25519.Sign(priv.Key, []byte(testKeyMsg)),
+		UntrustedComment: "signature from minisign secret key",
+		TrustedComment:   "timestamp:1614549543\tfile:message.txt",
+	}
+	sig.GlobalSignature = ed25519.Sign(priv.Key, sig.global())
+	if buf, err := sig.MarshalText(); err != nil || string(buf) != testKeySig {
+		t.Fatalf("signature does not match:\n%s", buf)
+	}
+}
@@ -24,6 +24,12 @@
 //
 // https://github.com/voidlinux/xbps/issues/146
 //
+// Newer xbps versions store signatures in .sig2 files which use the correct
+// sha256 algorithm identifier, they are handled by VerifySig2 and SignSig2.
+//
+// Ed25519 signatures in the minisign format are implemented by the
+// minisign subpackage.
+//
 // Note: golang also hardcodes the ASN1 prefix for performance reasons:
 //
 // https://github.com/golang/go/blob/dca707b/src/crypto/rsa/pkcs1v15.go#L210
@@ -55,7 +61,10 @@ func Verify(pub *rsa.PublicKey, hashed []byte, sig []byte) error {
 }
 
 // Sign a sha256 hash using xbps' quirks
-func Sign(priv *rsa.PrivateKey, hashed []byte) ([]byte, error) {
+//
+// The signer must be an RSA key that signs PKCS1v15 digests without
+// prefix for crypto.Hash(0) like *rsa.PrivateKey.
+func Sign(priv crypto.Signer, hashed []byte) ([]byte, error) {
 	tLen := len(hashed)
 	if tLen != 32 {
 		return nil, errHashMismatch
@@ -64,5 +73,24 @@ func Sign(priv *rsa.PrivateKey, hashed []byte) ([]byte, error) {
 	t := make([]byte, pLen+tLen)
 	copy(t[:pLen], sha1x256Prefix)
 	copy(t[pLen:], hashed)
-	return rsa.SignPKCS1v15(rand.Reader, priv, crypto.Hash(0), t)
+	return priv.Sign(rand.Reader, t, crypto.Hash(0))
+}
+
+// VerifySig2 verifies a sha256 hash signature in the format of xbps .sig2 files
+func VerifySig2(pub *rsa.PublicKey, hashed []byte, sig []byte) error {
+	if len(hashed) != 32 {
+		return errHashMismatch
+	}
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed, sig)
+}
+
+// SignSig2 signs a sha256 hash in the format of xbps .sig2 files
+//
+// The signer must be an RSA key that signs PKCS1v15 digests, like
+// *rsa.PrivateKey or keys of hardware tokens.
+func SignSig2(priv crypto.Signer, hashed []byte) ([]byte, error) {
+	if len(hashed) != 32 {
+		return nil, errHashMismatch
+	}
+	return priv.Sign(rand.Reader, hashed, crypto.SHA256)
 }
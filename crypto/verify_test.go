@@ -1,9 +1,12 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
+	"errors"
 	"io"
 	"os"
 	"path"
@@ -12,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/Duncaen/go-xbps/repo"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 var root = "/"
@@ -97,3 +101,91 @@ func TestSign(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSignSig2(t *testing.T) {
+	msg := "Hello World"
+	hashed := sha256.Sum256([]byte(msg))
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignSig2(priv, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.Public().(*rsa.PublicKey)
+	if err := VerifySig2(pub, hashed[:], sig); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(pub, hashed[:], sig); err == nil {
+		t.Fatal("sig2 signature verified with the legacy format")
+	}
+}
+
+type signer struct {
+	crypto.Signer
+}
+
+func TestSignSigner(t *testing.T) {
+	hashed := sha256.Sum256([]byte("Hello World"))
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wrapped to only use the crypto.Signer interface
+	s := signer{priv}
+	sig, err := Sign(s, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(&priv.PublicKey, hashed[:], sig); err != nil {
+		t.Fatal(err)
+	}
+	sig, err = SignSig2(s, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySig2(&priv.PublicKey, hashed[:], sig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentSigner(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	a := keyring.(agent.ExtendedAgent)
+	if _, err := NewAgentSigner(a, &other.PublicKey); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	s, err := NewAgentSigner(a, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("Hello World")
+	sig, err := s.SignSig2(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256(msg)
+	if err := VerifySig2(&priv.PublicKey, hashed[:], sig); err != nil {
+		t.Fatal(err)
+	}
+	// the signature matches signing the hash with the private key
+	expect, err := SignSig2(priv, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, expect) {
+		t.Fatal("agent signature does not match SignSig2")
+	}
+}
@@ -0,0 +1,240 @@
+package fileindex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// magic identifies the on-disk format and its version
+const magic = "XBPSFIDX\x01"
+
+// ErrFormat is returned for data that is not a file index
+var ErrFormat = errors.New("invalid file index")
+
+// The on-disk format is a zstd compressed stream of:
+//
+//	magic
+//	uvarint number of packages, followed by the length prefixed pkgvers
+//	uvarint number of entries, followed by the entries sorted by path
+//
+// Entries are front coded, each entry stores the length of the prefix it
+// shares with the previous path followed by the remaining suffix:
+//
+//	uvarint shared, uvarint suffix length, suffix
+//	uvarint package, byte kind, uvarint target length, target
+
+type writer struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *writer) uvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	w.w.Write(w.buf[:n])
+}
+
+func (w *writer) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.w.WriteString(s)
+}
+
+// WriteTo writes the index in the on-disk format to w
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	ix.sort()
+	cw := &countWriter{w: w}
+	enc, err := zstd.NewWriter(cw)
+	if err != nil {
+		return 0, err
+	}
+	wr := &writer{w: bufio.NewWriter(enc)}
+	wr.w.WriteString(magic)
+	wr.uvarint(uint64(len(ix.pkgs)))
+	for _, pkg := range ix.pkgs {
+		wr.string(pkg)
+	}
+	wr.uvarint(uint64(len(ix.entries)))
+	prev := ""
+	for _, e := range ix.entries {
+		shared := commonPrefix(prev, e.path)
+		wr.uvarint(uint64(shared))
+		wr.string(e.path[shared:])
+		wr.uvarint(uint64(e.pkg))
+		wr.w.WriteByte(byte(e.kind))
+		wr.string(e.target)
+		prev = e.path
+	}
+	if err := wr.w.Flush(); err != nil {
+		enc.Close()
+		return cw.n, err
+	}
+	if err := enc.Close(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countReader) Read(b []byte) (int, error) {
+	n, err := cr.r.Read(b)
+	cr.n += int64(n)
+	return n, err
+}
+
+type reader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		r.err = err
+	}
+	return v
+}
+
+func (r *reader) string(max int) string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(max) {
+		r.err = ErrFormat
+		return ""
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		r.err = err
+	}
+	return string(buf)
+}
+
+// maxString limits the length of strings to reject corrupt data early
+const maxString = 1 << 16
+
+// ReadFrom reads an index in the on-disk format from r, replacing the
+// contents of the index.
+func (ix *Index) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: r}
+	dec, err := zstd.NewReader(cr)
+	if err != nil {
+		return 0, err
+	}
+	defer dec.Close()
+	rd := &reader{r: bufio.NewReader(dec)}
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(rd.r, head); err != nil || string(head) != magic {
+		return cr.n, ErrFormat
+	}
+	npkgs := rd.uvarint()
+	var pkgs []string
+	for i := uint64(0); i < npkgs && rd.err == nil; i++ {
+		pkgs = append(pkgs, rd.string(maxString))
+	}
+	nentries := rd.uvarint()
+	var entries []entry
+	prev := ""
+	for i := uint64(0); i < nentries && rd.err == nil; i++ {
+		shared := rd.uvarint()
+		suffix := rd.string(maxString)
+		pkg := rd.uvarint()
+		kind, err := rd.r.ReadByte()
+		if err != nil && rd.err == nil {
+			rd.err = err
+		}
+		target := rd.string(maxString)
+		if rd.err != nil {
+			break
+		}
+		if shared > uint64(len(prev)) || pkg >= uint64(len(pkgs)) || Kind(kind) > ConfFile {
+			rd.err = ErrFormat
+			break
+		}
+		e := entry{path: prev[:shared] + suffix, pkg: int(pkg), kind: Kind(kind), target: target}
+		// lookups rely on the order, the index is not sorted again
+		if len(entries) > 0 && entryLess(pkgs, e, entries[len(entries)-1]) {
+			rd.err = fmt.Errorf("%w: entries out of order at %q", ErrFormat, e.path)
+			break
+		}
+		entries = append(entries, e)
+		prev = e.path
+	}
+	if rd.err != nil {
+		if errors.Is(rd.err, ErrFormat) {
+			return cr.n, rd.err
+		}
+		return cr.n, fmt.Errorf("%w: %w", ErrFormat, rd.err)
+	}
+	ix.pkgs, ix.entries, ix.sorted = pkgs, entries, true
+	return cr.n, nil
+}
+
+// Open reads the index file at path
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ix := New()
+	if _, err := ix.ReadFrom(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ix, nil
+}
+
+// Save atomically writes the index to the file at path
+func (ix *Index) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fileindex-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := ix.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,231 @@
+// Package fileindex implements an index of the files owned by packages,
+// answering which package ships a path like xbps-query -o and xlocate.
+//
+// An index is built from package file lists, either from the package
+// database of an installed system or from the binary packages of a
+// repository, and can be stored in a compact on-disk format.
+package fileindex
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+// Kind is the kind of a file list entry
+type Kind uint8
+
+const (
+	// File is a regular file
+	File Kind = iota
+	// Link is a symbolic link
+	Link
+	// ConfFile is a configuration file
+	ConfFile
+)
+
+func (k Kind) String() string {
+	switch k {
+	case File:
+		return "file"
+	case Link:
+		return "link"
+	case ConfFile:
+		return "conf_file"
+	}
+	return fmt.Sprintf("Kind(%d)", k)
+}
+
+// Owner is a path owned by a package
+type Owner struct {
+	// Path is the absolute path of the file
+	Path string `json:"path"`
+	// PkgVer is the package that owns the path
+	PkgVer string `json:"pkgver"`
+	// Kind is the kind of the file
+	Kind Kind `json:"kind"`
+	// Target is the target of symbolic links
+	Target string `json:"target,omitempty"`
+}
+
+func (o Owner) String() string {
+	if o.Target != "" {
+		return fmt.Sprintf("%s: %s -> %s (%s)", o.PkgVer, o.Path, o.Target, o.Kind)
+	}
+	return fmt.Sprintf("%s: %s (%s)", o.PkgVer, o.Path, o.Kind)
+}
+
+// entry is an indexed path referencing the package by its position
+type entry struct {
+	path   string
+	pkg    int
+	kind   Kind
+	target string
+}
+
+// Index maps paths to the packages that own them.
+//
+// Packages are added with Add, lookups sort the index on first use.
+// Lookups are safe for concurrent use, Add and ReadFrom are not.
+type Index struct {
+	pkgs    []string
+	entries []entry
+	// mu guards the lazy sort of the entries
+	mu     sync.Mutex
+	sorted bool
+}
+
+// New returns an empty index
+func New() *Index {
+	return &Index{sorted: true}
+}
+
+// Add adds the files of the package to the index.
+//
+// Directories are not indexed, they are usually shared by many packages.
+func (ix *Index) Add(pkgver string, files *binpkg.Files) {
+	pkg := len(ix.pkgs)
+	ix.pkgs = append(ix.pkgs, pkgver)
+	for _, l := range []struct {
+		entries []binpkg.Entry
+		kind    Kind
+	}{{files.Files, File}, {files.Links, Link}, {files.ConfFiles, ConfFile}} {
+		for _, e := range l.entries {
+			ix.entries = append(ix.entries, entry{e.File, pkg, l.kind, e.Target})
+		}
+	}
+	ix.sorted = false
+}
+
+// AddPkgDB adds the installed packages of the package database
+func (ix *Index) AddPkgDB(db *pkgdb.DB) error {
+	for _, name := range db.Names() {
+		files, err := db.Files(name)
+		if err != nil {
+			// packages without files, like meta packages, have no file list
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		ix.Add(db.Packages[name].PkgVer, files)
+	}
+	return nil
+}
+
+// AddRepository adds the packages in the repository index, the binary
+// packages are read from the repository directory dir.
+func (ix *Index) AddRepository(r *repo.Repository, dir string) error {
+	names := make([]string, 0, len(r.Index))
+	for name := range r.Index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkg := r.Index[name]
+		meta, err := binpkg.OpenMetadata(filepath.Join(dir, pkg.Filename()))
+		if err != nil {
+			return err
+		}
+		ix.Add(pkg.PkgVer, &meta.Files)
+	}
+	return nil
+}
+
+// Len returns the number of indexed paths
+func (ix *Index) Len() int {
+	return len(ix.entries)
+}
+
+// Packages returns the indexed packages
+func (ix *Index) Packages() []string {
+	return append([]string(nil), ix.pkgs...)
+}
+
+// entryLess orders entries by path and then by pkgver
+func entryLess(pkgs []string, a, b entry) bool {
+	if a.path != b.path {
+		return a.path < b.path
+	}
+	return pkgs[a.pkg] < pkgs[b.pkg]
+}
+
+func (ix *Index) sort() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.sorted {
+		return
+	}
+	sort.SliceStable(ix.entries, func(i, j int) bool {
+		return entryLess(ix.pkgs, ix.entries[i], ix.entries[j])
+	})
+	ix.sorted = true
+}
+
+func (ix *Index) owner(e entry) Owner {
+	return Owner{Path: e.path, PkgVer: ix.pkgs[e.pkg], Kind: e.kind, Target: e.target}
+}
+
+// prefix returns the entries whose path starts with prefix
+func (ix *Index) prefix(prefix string) []entry {
+	ix.sort()
+	i := sort.Search(len(ix.entries), func(i int) bool { return ix.entries[i].path >= prefix })
+	j := i
+	for j < len(ix.entries) && strings.HasPrefix(ix.entries[j].path, prefix) {
+		j++
+	}
+	return ix.entries[i:j]
+}
+
+// Lookup returns the owners of the exact path
+func (ix *Index) Lookup(p string) []Owner {
+	var res []Owner
+	for _, e := range ix.prefix(p) {
+		if e.path == p {
+			res = append(res, ix.owner(e))
+		}
+	}
+	return res
+}
+
+// Glob returns the owners of paths matching the pattern using the
+// syntax of path.Match.
+func (ix *Index) Glob(pattern string) ([]Owner, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	// only entries starting with the literal prefix of the pattern can match
+	lit := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i != -1 {
+		lit = pattern[:i]
+	}
+	var res []Owner
+	for _, e := range ix.prefix(lit) {
+		if ok, _ := path.Match(pattern, e.path); ok {
+			res = append(res, ix.owner(e))
+		}
+	}
+	return res, nil
+}
+
+// Regexp returns the owners of paths matching the regular expression
+func (ix *Index) Regexp(re *regexp.Regexp) []Owner {
+	ix.sort()
+	var res []Owner
+	for _, e := range ix.entries {
+		if re.MatchString(e.path) {
+			res = append(res, ix.owner(e))
+		}
+	}
+	return res
+}
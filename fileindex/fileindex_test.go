@@ -0,0 +1,224 @@
+package fileindex
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func testIndex() *Index {
+	ix := New()
+	ix.Add("foo-1.0_1", &binpkg.Files{
+		Files:     []binpkg.Entry{{File: "/usr/bin/foo"}, {File: "/usr/share/man/man1/foo.1"}},
+		Links:     []binpkg.Entry{{File: "/usr/bin/foolink", Target: "foo"}},
+		ConfFiles: []binpkg.Entry{{File: "/etc/foo.conf"}},
+		Dirs:      []binpkg.Entry{{File: "/usr/share/foo"}},
+	})
+	ix.Add("bar-2.0_1", &binpkg.Files{
+		Files: []binpkg.Entry{{File: "/usr/bin/bar"}, {File: "/usr/bin/foo"}},
+	})
+	return ix
+}
+
+func paths(owners []Owner) []string {
+	var res []string
+	for _, o := range owners {
+		res = append(res, o.PkgVer+":"+o.Path)
+	}
+	return res
+}
+
+func testLookups(t *testing.T, ix *Index) {
+	t.Helper()
+	if got := paths(ix.Lookup("/usr/bin/foo")); !reflect.DeepEqual(got, []string{"bar-2.0_1:/usr/bin/foo", "foo-1.0_1:/usr/bin/foo"}) {
+		t.Errorf("Lookup: unexpected owners %v", got)
+	}
+	if got := ix.Lookup("/usr/bin/fo"); got != nil {
+		t.Errorf("Lookup: unexpected prefix match %v", got)
+	}
+	if got := ix.Lookup("/usr/share/foo"); got != nil {
+		t.Errorf("Lookup: directories should not be indexed: %v", got)
+	}
+	link := ix.Lookup("/usr/bin/foolink")
+	if len(link) != 1 || link[0].Kind != Link || link[0].Target != "foo" {
+		t.Errorf("Lookup: unexpected link %+v", link)
+	}
+	glob, err := ix.Glob("/usr/bin/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(glob); !reflect.DeepEqual(got, []string{"bar-2.0_1:/usr/bin/bar", "bar-2.0_1:/usr/bin/foo", "foo-1.0_1:/usr/bin/foo", "foo-1.0_1:/usr/bin/foolink"}) {
+		t.Errorf("Glob: unexpected owners %v", got)
+	}
+	if _, err := ix.Glob("[/usr"); err == nil {
+		t.Errorf("Glob: expected error for bad pattern")
+	}
+	if got := paths(ix.Regexp(regexp.MustCompile(`\.(conf|1)$`))); !reflect.DeepEqual(got, []string{"foo-1.0_1:/etc/foo.conf", "foo-1.0_1:/usr/share/man/man1/foo.1"}) {
+		t.Errorf("Regexp: unexpected owners %v", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	testLookups(t, testIndex())
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := testIndex().WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+	ix := New()
+	if _, err := ix.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if ix.Len() != 6 {
+		t.Fatalf("expected 6 entries, got %d", ix.Len())
+	}
+	testLookups(t, ix)
+
+	path := filepath.Join(t.TempDir(), "files.idx")
+	if err := testIndex().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if ix, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	testLookups(t, ix)
+
+	if _, err := New().ReadFrom(bytes.NewReader([]byte("garbage"))); !errors.Is(err, ErrFormat) {
+		t.Fatalf("expected ErrFormat, got %v", err)
+	}
+
+	// an index with entries out of order is rejected
+	unsorted := &Index{
+		pkgs:    []string{"foo-1.0_1"},
+		entries: []entry{{path: "/usr/bin/foo"}, {path: "/usr/bin/bar"}},
+		sorted:  true,
+	}
+	buf.Reset()
+	if _, err := unsorted.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New().ReadFrom(&buf); !errors.Is(err, ErrFormat) {
+		t.Fatalf("expected ErrFormat, got %v", err)
+	}
+}
+
+func TestLookupConcurrent(t *testing.T) {
+	ix := testIndex()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := ix.Lookup("/usr/bin/foo"); len(got) != 2 {
+				t.Errorf("Lookup: unexpected owners %v", got)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAddRepository(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "foo-1.0_1.x86_64.xbps"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := binpkg.NewWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := &binpkg.Metadata{
+		Props: repo.Package{PkgVer: "foo-1.0_1", Architecture: "x86_64"},
+		Files: binpkg.Files{Files: []binpkg.Entry{{File: "/usr/bin/foo"}}},
+	}
+	if err := w.WriteMetadata(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	r := &repo.Repository{Index: map[string]repo.Package{"foo": meta.Props}}
+	ix := New()
+	if err := ix.AddRepository(r, dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(ix.Lookup("/usr/bin/foo")); !reflect.DeepEqual(got, []string{"foo-1.0_1:/usr/bin/foo"}) {
+		t.Fatalf("unexpected owners %v", got)
+	}
+}
+
+func TestAddPkgDB(t *testing.T) {
+	root := t.TempDir()
+	metadir := filepath.Join(root, pkgdb.MetaDir)
+	if err := os.MkdirAll(metadir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// bar is a meta package without a file list
+	db := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>bar</key>
+	<dict>
+		<key>pkgver</key>
+		<string>bar-1.0_1</string>
+		<key>state</key>
+		<string>installed</string>
+	</dict>
+	<key>foo</key>
+	<dict>
+		<key>pkgver</key>
+		<string>foo-1.0_1</string>
+		<key>state</key>
+		<string>installed</string>
+	</dict>
+</dict>
+</plist>`
+	files := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>files</key>
+	<array>
+		<dict>
+			<key>file</key>
+			<string>/usr/bin/foo</string>
+		</dict>
+	</array>
+</dict>
+</plist>`
+	if err := os.WriteFile(filepath.Join(metadir, "pkgdb-"+pkgdb.Version+".plist"), []byte(db), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(metadir, ".foo-files.plist"), []byte(files), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pdb, err := pkgdb.Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix := New()
+	if err := ix.AddPkgDB(pdb); err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(ix.Lookup("/usr/bin/foo")); !reflect.DeepEqual(got, []string{"foo-1.0_1:/usr/bin/foo"}) {
+		t.Fatalf("unexpected owners %v", got)
+	}
+}
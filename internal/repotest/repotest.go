@@ -0,0 +1,142 @@
+// Package repotest implements signed repositories served over http for tests.
+package repotest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/crypto"
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/repo/uri"
+)
+
+// Repo is a repository signed with an RSA key and served over http
+type Repo struct {
+	*repo.Repository
+	// Dir is the directory served as repository
+	Dir string
+	// URL is the base url of the http server
+	URL string
+	// Key is the key the packages are signed with
+	Key *rsa.PrivateKey
+	// Hook is called with each request before it is served
+	Hook func(*http.Request)
+	srv  *httptest.Server
+}
+
+// New serves an empty repository for arch, the server is closed with the test
+func New(t testing.TB, arch string) *Repo {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Repo{Dir: t.TempDir(), Key: key}
+	r.Repository = &repo.Repository{
+		Arch:  arch,
+		Index: make(map[string]repo.Package),
+		Meta: &repo.Meta{
+			Key:      pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+			Size:     1024,
+			SignedBy: "Test <test@example.org>",
+		},
+	}
+	fs := http.FileServer(http.Dir(r.Dir))
+	r.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.Hook != nil {
+			r.Hook(req)
+		}
+		fs.ServeHTTP(w, req)
+	}))
+	t.Cleanup(r.srv.Close)
+	r.URL = r.srv.URL
+	if r.URI, err = uri.Parse(r.URL); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Close shuts down the http server
+func (r *Repo) Close() {
+	r.srv.Close()
+}
+
+// Add writes a package of arch with its .sig2 signature and adds it to the index
+func (r *Repo) Add(t testing.TB, pv, arch, content string) repo.Package {
+	t.Helper()
+	p, err := pkgver.Parse(pv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte(content))
+	pkg := repo.Package{
+		PkgVer:         pv,
+		Architecture:   arch,
+		FilenameSHA256: hex.EncodeToString(hash[:]),
+		FilenameSize:   int64(len(content)),
+	}
+	sig, err := crypto.SignSig2(r.Key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.Dir, pkg.Filename()), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.Dir, pkg.Filename()+".sig2"), sig, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r.Index[p.Name] = pkg
+	return pkg
+}
+
+// WriteRepodata writes the repository data and returns its path
+func (r *Repo) WriteRepodata(t testing.TB) string {
+	t.Helper()
+	path := filepath.Join(r.Dir, r.Arch+"-repodata")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := r.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// SignRepodata writes the detached minisign signature of the repository data
+func (r *Repo) SignRepodata(t testing.TB, key *minisign.PrivateKey) {
+	t.Helper()
+	path := filepath.Join(r.Dir, r.Arch+"-repodata")
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sig, err := minisign.Sign(key, f, "file:"+filepath.Base(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := sig.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+minisign.Ext, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+//go:build ignore
+
+// Gen generates the table of SPDX license identifiers from the licenses.json
+// of the SPDX license list data.
+//
+// Usage:
+//
+//	go run gen.go [-i licenses.json] [-o spdx.go]
+//
+// Without -i the licenses.json of the pinned license list version is fetched.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// version is the version of the SPDX license list
+const version = "v3.23"
+
+const url = "https://raw.githubusercontent.com/spdx/license-list-data/" + version + "/json/licenses.json"
+
+type list struct {
+	Version  string `json:"licenseListVersion"`
+	Licenses []struct {
+		ID         string `json:"licenseId"`
+		OSI        bool   `json:"isOsiApproved"`
+		Deprecated bool   `json:"isDeprecatedLicenseId"`
+	} `json:"licenses"`
+}
+
+func fetch() ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gen: ")
+	input := flag.String("i", "", "licenses.json file")
+	output := flag.String("o", "spdx.go", "output file")
+	flag.Parse()
+
+	var buf []byte
+	var err error
+	if *input != "" {
+		buf, err = os.ReadFile(*input)
+	} else {
+		buf, err = fetch()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	var l list
+	if err := json.Unmarshal(buf, &l); err != nil {
+		log.Fatal(err)
+	}
+	if len(l.Licenses) == 0 {
+		log.Fatal("no licenses in license list")
+	}
+	sort.Slice(l.Licenses, func(i, j int) bool { return l.Licenses[i].ID < l.Licenses[j].ID })
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gen.go from the SPDX license list %s. DO NOT EDIT.\n\n", l.Version)
+	fmt.Fprintf(&b, "package license\n\n")
+	fmt.Fprintf(&b, "// ListVersion is the version of the SPDX license list\n")
+	fmt.Fprintf(&b, "const ListVersion = %q\n\n", l.Version)
+	fmt.Fprintf(&b, "// licenses maps the SPDX license identifiers to their flags\n")
+	fmt.Fprintf(&b, "var licenses = map[string]flags{\n")
+	for _, lic := range l.Licenses {
+		f := "0"
+		switch {
+		case lic.OSI && lic.Deprecated:
+			f = "osi | deprecated"
+		case lic.OSI:
+			f = "osi"
+		case lic.Deprecated:
+			f = "deprecated"
+		}
+		fmt.Fprintf(&b, "\t%q: %s,\n", lic.ID, f)
+	}
+	fmt.Fprintf(&b, "}\n")
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
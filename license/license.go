@@ -0,0 +1,288 @@
+// Package license implements parsing package licenses into SPDX license
+// expressions and checking them against license policies.
+//
+// Void packages list their licenses separated by commas, all of which
+// apply, each license is either an SPDX expression, a custom license
+// prefixed with custom: or Public Domain:
+//
+//	GPL-2.0-or-later, custom:Hybrid
+//	MIT OR Apache-2.0
+//
+// Parse converts them to a single expression where commas are AND
+// operators and custom licenses are LicenseRef identifiers.
+package license
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSyntax is returned for malformed license expressions
+var ErrSyntax = errors.New("malformed license expression")
+
+// Expr is a license expression, one of License, And or Or
+type Expr interface {
+	// String returns the SPDX representation of the expression
+	String() string
+	walk(fn func(License))
+}
+
+// License is a single license
+type License struct {
+	// ID is the SPDX license identifier or the name of a custom license
+	ID string
+	// OrLater is true for licenses with a trailing +
+	OrLater bool
+	// Exception is the license exception following WITH
+	Exception string
+	// Custom is true for custom licenses
+	Custom bool
+}
+
+// SPDXID returns the SPDX license identifier, custom licenses are
+// LicenseRef identifiers.
+func (l License) SPDXID() string {
+	if !l.Custom {
+		return l.ID
+	}
+	ref := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, l.ID)
+	return "LicenseRef-" + ref
+}
+
+func (l License) String() string {
+	s := l.SPDXID()
+	if l.OrLater {
+		s += "+"
+	}
+	if l.Exception != "" {
+		s += " WITH " + l.Exception
+	}
+	return s
+}
+
+func (l License) walk(fn func(License)) { fn(l) }
+
+// And is an expression where all licenses apply
+type And []Expr
+
+func (a And) String() string { return join(a, " AND ") }
+
+func (a And) walk(fn func(License)) {
+	for _, e := range a {
+		e.walk(fn)
+	}
+}
+
+// Or is an expression where one of the licenses can be chosen
+type Or []Expr
+
+func (o Or) String() string { return join(o, " OR ") }
+
+func (o Or) walk(fn func(License)) {
+	for _, e := range o {
+		e.walk(fn)
+	}
+}
+
+func join(exprs []Expr, sep string) string {
+	s := make([]string, len(exprs))
+	for i, e := range exprs {
+		s[i] = e.String()
+		if _, ok := e.(License); !ok {
+			s[i] = "(" + s[i] + ")"
+		}
+	}
+	return strings.Join(s, sep)
+}
+
+// Licenses returns all licenses of the expression
+func Licenses(e Expr) []License {
+	var res []License
+	e.walk(func(l License) { res = append(res, l) })
+	return res
+}
+
+// Parse parses a package license string
+func Parse(s string) (Expr, error) {
+	var and And
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("%q: empty license: %w", s, ErrSyntax)
+		}
+		var e Expr
+		if strings.EqualFold(part, "Public Domain") {
+			e = License{ID: "Public-Domain", Custom: true}
+		} else {
+			p := parser{tokens: tokenize(part)}
+			var err error
+			if e, err = p.or(); err != nil {
+				return nil, fmt.Errorf("%q: %w", s, err)
+			}
+			if tok := p.peek(); tok != "" {
+				return nil, fmt.Errorf("%q: unexpected %q: %w", s, tok, ErrSyntax)
+			}
+		}
+		if a, ok := e.(And); ok {
+			and = append(and, a...)
+		} else {
+			and = append(and, e)
+		}
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// MustParse is like Parse but panics on error
+func MustParse(s string) Expr {
+	e, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func isOp(tok, op string) bool {
+	return strings.EqualFold(tok, op)
+}
+
+// tokenize splits an expression into parentheses, operators and licenses,
+// custom licenses may contain spaces.
+func tokenize(s string) []string {
+	var res []string
+	for _, f := range strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)) {
+		if n := len(res); n > 0 && strings.HasPrefix(res[n-1], "custom:") &&
+			f != "(" && f != ")" && !isOp(f, "AND") && !isOp(f, "OR") && !isOp(f, "WITH") {
+			res[n-1] += " " + f
+			continue
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+type parser struct {
+	tokens []string
+}
+
+func (p *parser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *parser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.tokens = p.tokens[1:]
+	}
+	return tok
+}
+
+func (p *parser) or() (Expr, error) {
+	var or Or
+	for {
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, e)
+		if !isOp(p.peek(), "OR") {
+			break
+		}
+		p.next()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	var and And
+	for {
+		e, err := p.with()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+		if !isOp(p.peek(), "AND") {
+			break
+		}
+		p.next()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) with() (Expr, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end: %w", ErrSyntax)
+	case tok == "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ): %w", ErrSyntax)
+		}
+		return e, nil
+	}
+	l, err := parseLicense(tok)
+	if err != nil {
+		return nil, err
+	}
+	if isOp(p.peek(), "WITH") {
+		p.next()
+		l.Exception = p.next()
+		if !validID(l.Exception) {
+			return nil, fmt.Errorf("invalid exception %q: %w", l.Exception, ErrSyntax)
+		}
+	}
+	return l, nil
+}
+
+func parseLicense(tok string) (License, error) {
+	switch {
+	case strings.HasPrefix(tok, "custom:"):
+		if name := strings.TrimPrefix(tok, "custom:"); name != "" {
+			return License{ID: name, Custom: true}, nil
+		}
+	case strings.HasPrefix(tok, "LicenseRef-"):
+		if name := strings.TrimPrefix(tok, "LicenseRef-"); validID(name) {
+			return License{ID: name, Custom: true}, nil
+		}
+	default:
+		l := License{ID: strings.TrimSuffix(tok, "+"), OrLater: strings.HasSuffix(tok, "+")}
+		if validID(l.ID) && !isOp(l.ID, "AND") && !isOp(l.ID, "OR") && !isOp(l.ID, "WITH") {
+			return l, nil
+		}
+	}
+	return License{}, fmt.Errorf("invalid license %q: %w", tok, ErrSyntax)
+}
+
+// validID returns true if s is a valid SPDX idstring
+func validID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package license
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+var parseTests = []struct {
+	in, out string
+}{
+	{"MIT", "MIT"},
+	{"GPL-2.0-or-later, MIT", "GPL-2.0-or-later AND MIT"},
+	{"MIT OR Apache-2.0", "MIT OR Apache-2.0"},
+	{"GPL-2.0+ WITH Classpath-exception-2.0", "GPL-2.0+ WITH Classpath-exception-2.0"},
+	{"(MIT OR Apache-2.0) AND BSD-3-Clause", "(MIT OR Apache-2.0) AND BSD-3-Clause"},
+	{"MIT AND BSD-2-Clause OR ISC", "(MIT AND BSD-2-Clause) OR ISC"},
+	{"LGPL-2.1-only, custom:Hybrid", "LGPL-2.1-only AND LicenseRef-Hybrid"},
+	{"custom:Some License", "LicenseRef-Some-License"},
+	{"Public Domain, MIT AND ISC", "LicenseRef-Public-Domain AND MIT AND ISC"},
+	{"LicenseRef-Foo", "LicenseRef-Foo"},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		e, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.in, err)
+		}
+		if s := e.String(); s != tt.out {
+			t.Errorf("%q: expected %q, got %q", tt.in, tt.out, s)
+		}
+	}
+	for _, s := range []string{"", "MIT,", "MIT OR", "(MIT", "MIT)", "MIT AND AND ISC", "M!T", "MIT WITH", "custom:"} {
+		if _, err := Parse(s); !errors.Is(err, ErrSyntax) {
+			t.Errorf("%q: expected ErrSyntax, got %v", s, err)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	for _, tt := range []struct {
+		id                     string
+		known, osi, deprecated bool
+	}{
+		{"MIT", true, true, false},
+		{"GPL-2.0", true, true, true},
+		{"GPL-2.0-only", true, true, false},
+		{"StandardML-NJ", true, false, true},
+		{"Vim", true, false, false},
+		{"Frobnicate-1.0", false, false, false},
+	} {
+		if Known(tt.id) != tt.known || OSI(tt.id) != tt.osi || Deprecated(tt.id) != tt.deprecated {
+			t.Errorf("%s: expected known=%v osi=%v deprecated=%v", tt.id, tt.known, tt.osi, tt.deprecated)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	deny := &Policy{Deny: []string{"AGPL-*"}}
+	osi := &Policy{OSIOnly: true, Allow: []string{"LicenseRef-Hybrid"}}
+	tests := []struct {
+		p        *Policy
+		license  string
+		rejected []License
+	}{
+		{deny, "MIT", nil},
+		{deny, "AGPL-3.0-only", []License{{ID: "AGPL-3.0-only"}}},
+		{deny, "AGPL-3.0-only OR MIT", nil},
+		{deny, "AGPL-3.0-only, MIT", []License{{ID: "AGPL-3.0-only"}}},
+		{osi, "MIT, custom:Hybrid", nil},
+		{osi, "WTFPL OR CC0-1.0", []License{{ID: "WTFPL"}, {ID: "CC0-1.0"}}},
+		{osi, "custom:Other", []License{{ID: "Other", Custom: true}}},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Check(MustParse(tt.license)); !reflect.DeepEqual(got, tt.rejected) {
+			t.Errorf("%q: expected rejected %v, got %v", tt.license, tt.rejected, got)
+		}
+	}
+}
+
+func TestPolicyRepository(t *testing.T) {
+	r := &repo.Repository{Index: map[string]repo.Package{
+		"foo": {PkgVer: "foo-1.0_1", License: "AGPL-3.0-or-later, MIT"},
+		"bar": {PkgVer: "bar-1.0_1", License: "MIT OR"},
+		"baz": {PkgVer: "baz-1.0_1", License: "BSD-2-Clause"},
+	}}
+	got := (&Policy{Deny: []string{"AGPL-*"}}).Repository(r)
+	if len(got) != 2 || got[0].PkgVer != "bar-1.0_1" || got[0].Error == "" ||
+		got[1].PkgVer != "foo-1.0_1" || !reflect.DeepEqual(got[1].Rejected, []string{"AGPL-3.0-or-later"}) {
+		t.Fatalf("unexpected violations %+v", got)
+	}
+}
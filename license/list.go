@@ -0,0 +1,34 @@
+package license
+
+//go:generate go run gen.go -o spdx.go
+
+// flags are the properties of a license in the SPDX license list
+type flags uint8
+
+const (
+	// osi marks licenses approved by the Open Source Initiative
+	osi flags = 1 << iota
+	// deprecated marks identifiers that should no longer be used
+	deprecated
+)
+
+// Known returns true if id is a known SPDX license identifier
+func Known(id string) bool {
+	_, ok := licenses[id]
+	return ok
+}
+
+// OSI returns true if id is an OSI approved license
+func OSI(id string) bool {
+	return licenses[id]&osi != 0
+}
+
+// Deprecated returns true if id is a deprecated SPDX license identifier
+func Deprecated(id string) bool {
+	return licenses[id]&deprecated != 0
+}
+
+// Known returns true if the license is a known SPDX license or custom
+func (l License) Known() bool {
+	return l.Custom || Known(l.ID)
+}
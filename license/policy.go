@@ -0,0 +1,124 @@
+package license
+
+import (
+	"path"
+	"sort"
+
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+// Policy decides which licenses are acceptable.
+//
+// A license is rejected if it matches Deny and accepted if it matches
+// Allow. Other licenses are accepted if they are OSI approved with OSIOnly
+// set, otherwise only if Allow is empty. Patterns are globs matched
+// against the SPDX identifier, like AGPL-* or LicenseRef-*.
+type Policy struct {
+	// Deny are patterns of rejected licenses
+	Deny []string `json:"deny,omitempty"`
+	// Allow are patterns of accepted licenses
+	Allow []string `json:"allow,omitempty"`
+	// OSIOnly rejects licenses that are not OSI approved
+	OSIOnly bool `json:"osi_only,omitempty"`
+}
+
+func match(patterns []string, id string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Accepts returns true if the license is acceptable
+func (p *Policy) Accepts(l License) bool {
+	id := l.SPDXID()
+	switch {
+	case match(p.Deny, id):
+		return false
+	case match(p.Allow, id):
+		return true
+	case p.OSIOnly:
+		return !l.Custom && OSI(l.ID)
+	}
+	return len(p.Allow) == 0
+}
+
+// Check returns the rejected licenses that prevent complying with the
+// expression, for Or expressions only if no choice is acceptable.
+func (p *Policy) Check(e Expr) []License {
+	switch e := e.(type) {
+	case License:
+		if p.Accepts(e) {
+			return nil
+		}
+		return []License{e}
+	case And:
+		var res []License
+		for _, x := range e {
+			res = append(res, p.Check(x)...)
+		}
+		return res
+	case Or:
+		var res []License
+		for _, x := range e {
+			rejected := p.Check(x)
+			if len(rejected) == 0 {
+				return nil
+			}
+			res = append(res, rejected...)
+		}
+		return res
+	}
+	return nil
+}
+
+// Violation is a package that does not comply with a policy
+type Violation struct {
+	// PkgVer is the package
+	PkgVer string `json:"pkgver"`
+	// License is the license string of the package
+	License string `json:"license"`
+	// Rejected are the rejected licenses
+	Rejected []string `json:"rejected,omitempty"`
+	// Error is the parse error of malformed licenses
+	Error string `json:"error,omitempty"`
+}
+
+// Packages returns the violations of the packages, like the packages of a
+// repository or a transaction, in order.
+func (p *Policy) Packages(pkgs []repo.Package) []Violation {
+	var res []Violation
+	for _, pkg := range pkgs {
+		v := Violation{PkgVer: pkg.PkgVer, License: pkg.License}
+		e, err := Parse(pkg.License)
+		if err != nil {
+			v.Error = err.Error()
+			res = append(res, v)
+			continue
+		}
+		for _, l := range p.Check(e) {
+			v.Rejected = append(v.Rejected, l.String())
+		}
+		if len(v.Rejected) > 0 {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// Repository returns the violations of the repository index ordered by
+// package name.
+func (p *Policy) Repository(r *repo.Repository) []Violation {
+	names := make([]string, 0, len(r.Index))
+	for name := range r.Index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pkgs := make([]repo.Package, len(names))
+	for i, name := range names {
+		pkgs[i] = r.Index[name]
+	}
+	return p.Packages(pkgs)
+}
@@ -0,0 +1,647 @@
+// Code generated by gen.go from the SPDX license list 3.23. DO NOT EDIT.
+
+package license
+
+// ListVersion is the version of the SPDX license list
+const ListVersion = "3.23"
+
+// licenses maps the SPDX license identifiers to their flags
+var licenses = map[string]flags{
+	"0BSD":                                 osi,
+	"AAL":                                  osi,
+	"ADSL":                                 0,
+	"AFL-1.1":                              osi,
+	"AFL-1.2":                              osi,
+	"AFL-2.0":                              osi,
+	"AFL-2.1":                              osi,
+	"AFL-3.0":                              osi,
+	"AGPL-1.0":                             deprecated,
+	"AGPL-1.0-only":                        0,
+	"AGPL-1.0-or-later":                    0,
+	"AGPL-3.0":                             osi | deprecated,
+	"AGPL-3.0-only":                        osi,
+	"AGPL-3.0-or-later":                    osi,
+	"AMDPLPA":                              0,
+	"AML":                                  0,
+	"AML-glslang":                          0,
+	"AMPAS":                                0,
+	"ANTLR-PD":                             0,
+	"ANTLR-PD-fallback":                    0,
+	"APAFML":                               0,
+	"APL-1.0":                              osi,
+	"APSL-1.0":                             osi,
+	"APSL-1.1":                             osi,
+	"APSL-1.2":                             osi,
+	"APSL-2.0":                             osi,
+	"ASWF-Digital-Assets-1.0":              0,
+	"ASWF-Digital-Assets-1.1":              0,
+	"Abstyles":                             0,
+	"AdaCore-doc":                          0,
+	"Adobe-2006":                           0,
+	"Adobe-Display-PostScript":             0,
+	"Adobe-Glyph":                          0,
+	"Adobe-Utopia":                         0,
+	"Afmparse":                             0,
+	"Aladdin":                              0,
+	"Apache-1.0":                           0,
+	"Apache-1.1":                           osi,
+	"Apache-2.0":                           osi,
+	"App-s2p":                              0,
+	"Arphic-1999":                          0,
+	"Artistic-1.0":                         osi,
+	"Artistic-1.0-Perl":                    osi,
+	"Artistic-1.0-cl8":                     osi,
+	"Artistic-2.0":                         osi,
+	"BSD-1-Clause":                         osi,
+	"BSD-2-Clause":                         osi,
+	"BSD-2-Clause-Darwin":                  0,
+	"BSD-2-Clause-FreeBSD":                 deprecated,
+	"BSD-2-Clause-NetBSD":                  deprecated,
+	"BSD-2-Clause-Patent":                  osi,
+	"BSD-2-Clause-Views":                   0,
+	"BSD-3-Clause":                         osi,
+	"BSD-3-Clause-Attribution":             0,
+	"BSD-3-Clause-Clear":                   0,
+	"BSD-3-Clause-HP":                      0,
+	"BSD-3-Clause-LBNL":                    osi,
+	"BSD-3-Clause-Modification":            0,
+	"BSD-3-Clause-No-Military-License":     0,
+	"BSD-3-Clause-No-Nuclear-License":      0,
+	"BSD-3-Clause-No-Nuclear-License-2014": 0,
+	"BSD-3-Clause-No-Nuclear-Warranty":     0,
+	"BSD-3-Clause-Open-MPI":                0,
+	"BSD-3-Clause-Sun":                     0,
+	"BSD-3-Clause-acpica":                  0,
+	"BSD-3-Clause-flex":                    0,
+	"BSD-4-Clause":                         0,
+	"BSD-4-Clause-Shortened":               0,
+	"BSD-4-Clause-UC":                      0,
+	"BSD-4.3RENO":                          0,
+	"BSD-4.3TAHOE":                         0,
+	"BSD-Advertising-Acknowledgement":      0,
+	"BSD-Attribution-HPND-disclaimer":      0,
+	"BSD-Inferno-Nettverk":                 0,
+	"BSD-Protection":                       0,
+	"BSD-Source-Code":                      0,
+	"BSD-Source-beginning-file":            0,
+	"BSD-Systemics":                        0,
+	"BSD-Systemics-W3Works":                0,
+	"BSL-1.0":                              osi,
+	"BUSL-1.1":                             0,
+	"Baekmuk":                              0,
+	"Bahyph":                               0,
+	"Barr":                                 0,
+	"Beerware":                             0,
+	"BitTorrent-1.0":                       0,
+	"BitTorrent-1.1":                       0,
+	"Bitstream-Charter":                    0,
+	"Bitstream-Vera":                       0,
+	"BlueOak-1.0.0":                        osi,
+	"Boehm-GC":                             0,
+	"Borceux":                              0,
+	"Brian-Gladman-2-Clause":               0,
+	"Brian-Gladman-3-Clause":               0,
+	"C-UDA-1.0":                            0,
+	"CAL-1.0":                              osi,
+	"CAL-1.0-Combined-Work-Exception":      osi,
+	"CATOSL-1.1":                           osi,
+	"CC-BY-1.0":                            0,
+	"CC-BY-2.0":                            0,
+	"CC-BY-2.5":                            0,
+	"CC-BY-2.5-AU":                         0,
+	"CC-BY-3.0":                            0,
+	"CC-BY-3.0-AT":                         0,
+	"CC-BY-3.0-AU":                         0,
+	"CC-BY-3.0-DE":                         0,
+	"CC-BY-3.0-IGO":                        0,
+	"CC-BY-3.0-NL":                         0,
+	"CC-BY-3.0-US":                         0,
+	"CC-BY-4.0":                            0,
+	"CC-BY-NC-1.0":                         0,
+	"CC-BY-NC-2.0":                         0,
+	"CC-BY-NC-2.5":                         0,
+	"CC-BY-NC-3.0":                         0,
+	"CC-BY-NC-3.0-DE":                      0,
+	"CC-BY-NC-4.0":                         0,
+	"CC-BY-NC-ND-1.0":                      0,
+	"CC-BY-NC-ND-2.0":                      0,
+	"CC-BY-NC-ND-2.5":                      0,
+	"CC-BY-NC-ND-3.0":                      0,
+	"CC-BY-NC-ND-3.0-DE":                   0,
+	"CC-BY-NC-ND-3.0-IGO":                  0,
+	"CC-BY-NC-ND-4.0":                      0,
+	"CC-BY-NC-SA-1.0":                      0,
+	"CC-BY-NC-SA-2.0":                      0,
+	"CC-BY-NC-SA-2.0-DE":                   0,
+	"CC-BY-NC-SA-2.0-FR":                   0,
+	"CC-BY-NC-SA-2.0-UK":                   0,
+	"CC-BY-NC-SA-2.5":                      0,
+	"CC-BY-NC-SA-3.0":                      0,
+	"CC-BY-NC-SA-3.0-DE":                   0,
+	"CC-BY-NC-SA-3.0-IGO":                  0,
+	"CC-BY-NC-SA-4.0":                      0,
+	"CC-BY-ND-1.0":                         0,
+	"CC-BY-ND-2.0":                         0,
+	"CC-BY-ND-2.5":                         0,
+	"CC-BY-ND-3.0":                         0,
+	"CC-BY-ND-3.0-DE":                      0,
+	"CC-BY-ND-4.0":                         0,
+	"CC-BY-SA-1.0":                         0,
+	"CC-BY-SA-2.0":                         0,
+	"CC-BY-SA-2.0-UK":                      0,
+	"CC-BY-SA-2.1-JP":                      0,
+	"CC-BY-SA-2.5":                         0,
+	"CC-BY-SA-3.0":                         0,
+	"CC-BY-SA-3.0-AT":                      0,
+	"CC-BY-SA-3.0-DE":                      0,
+	"CC-BY-SA-3.0-IGO":                     0,
+	"CC-BY-SA-4.0":                         0,
+	"CC-PDDC":                              0,
+	"CC0-1.0":                              0,
+	"CDDL-1.0":                             osi,
+	"CDDL-1.1":                             0,
+	"CDL-1.0":                              0,
+	"CDLA-Permissive-1.0":                  0,
+	"CDLA-Permissive-2.0":                  0,
+	"CDLA-Sharing-1.0":                     0,
+	"CECILL-1.0":                           0,
+	"CECILL-1.1":                           0,
+	"CECILL-2.0":                           0,
+	"CECILL-2.1":                           osi,
+	"CECILL-B":                             0,
+	"CECILL-C":                             0,
+	"CERN-OHL-1.1":                         0,
+	"CERN-OHL-1.2":                         0,
+	"CERN-OHL-P-2.0":                       osi,
+	"CERN-OHL-S-2.0":                       osi,
+	"CERN-OHL-W-2.0":                       osi,
+	"CFITSIO":                              0,
+	"CMU-Mach":                             0,
+	"CMU-Mach-nodoc":                       0,
+	"CNRI-Jython":                          0,
+	"CNRI-Python":                          osi,
+	"CNRI-Python-GPL-Compatible":           0,
+	"COIL-1.0":                             0,
+	"CPAL-1.0":                             osi,
+	"CPL-1.0":                              osi,
+	"CPOL-1.02":                            0,
+	"CUA-OPL-1.0":                          osi,
+	"Caldera":                              0,
+	"Caldera-no-preamble":                  0,
+	"ClArtistic":                           0,
+	"Clips":                                0,
+	"Community-Spec-1.0":                   0,
+	"Condor-1.1":                           0,
+	"Cornell-Lossless-JPEG":                0,
+	"Cronyx":                               0,
+	"Crossword":                            0,
+	"CrystalStacker":                       0,
+	"Cube":                                 0,
+	"D-FSL-1.0":                            0,
+	"DEC-3-Clause":                         0,
+	"DL-DE-BY-2.0":                         0,
+	"DL-DE-ZERO-2.0":                       0,
+	"DOC":                                  0,
+	"DRL-1.0":                              0,
+	"DRL-1.1":                              0,
+	"DSDP":                                 0,
+	"Dotseqn":                              0,
+	"ECL-1.0":                              osi,
+	"ECL-2.0":                              osi,
+	"EFL-1.0":                              osi,
+	"EFL-2.0":                              osi,
+	"EPICS":                                0,
+	"EPL-1.0":                              osi,
+	"EPL-2.0":                              osi,
+	"EUDatagrid":                           osi,
+	"EUPL-1.0":                             0,
+	"EUPL-1.1":                             osi,
+	"EUPL-1.2":                             osi,
+	"Elastic-2.0":                          0,
+	"Entessa":                              osi,
+	"ErlPL-1.1":                            0,
+	"Eurosym":                              0,
+	"FBM":                                  0,
+	"FDK-AAC":                              0,
+	"FSFAP":                                0,
+	"FSFAP-no-warranty-disclaimer":         0,
+	"FSFUL":                                0,
+	"FSFULLR":                              0,
+	"FSFULLRWD":                            0,
+	"FTL":                                  0,
+	"Fair":                                 osi,
+	"Ferguson-Twofish":                     0,
+	"Frameworx-1.0":                        osi,
+	"FreeBSD-DOC":                          0,
+	"FreeImage":                            0,
+	"Furuseth":                             0,
+	"GCR-docs":                             0,
+	"GD":                                   0,
+	"GFDL-1.1":                             deprecated,
+	"GFDL-1.1-invariants-only":             0,
+	"GFDL-1.1-invariants-or-later":         0,
+	"GFDL-1.1-no-invariants-only":          0,
+	"GFDL-1.1-no-invariants-or-later":      0,
+	"GFDL-1.1-only":                        0,
+	"GFDL-1.1-or-later":                    0,
+	"GFDL-1.2":                             deprecated,
+	"GFDL-1.2-invariants-only":             0,
+	"GFDL-1.2-invariants-or-later":         0,
+	"GFDL-1.2-no-invariants-only":          0,
+	"GFDL-1.2-no-invariants-or-later":      0,
+	"GFDL-1.2-only":                        0,
+	"GFDL-1.2-or-later":                    0,
+	"GFDL-1.3":                             deprecated,
+	"GFDL-1.3-invariants-only":             0,
+	"GFDL-1.3-invariants-or-later":         0,
+	"GFDL-1.3-no-invariants-only":          0,
+	"GFDL-1.3-no-invariants-or-later":      0,
+	"GFDL-1.3-only":                        0,
+	"GFDL-1.3-or-later":                    0,
+	"GL2PS":                                0,
+	"GLWTPL":                               0,
+	"GPL-1.0":                              deprecated,
+	"GPL-1.0+":                             deprecated,
+	"GPL-1.0-only":                         0,
+	"GPL-1.0-or-later":                     0,
+	"GPL-2.0":                              osi | deprecated,
+	"GPL-2.0+":                             osi | deprecated,
+	"GPL-2.0-only":                         osi,
+	"GPL-2.0-or-later":                     osi,
+	"GPL-2.0-with-GCC-exception":           deprecated,
+	"GPL-2.0-with-autoconf-exception":      deprecated,
+	"GPL-2.0-with-bison-exception":         deprecated,
+	"GPL-2.0-with-classpath-exception":     deprecated,
+	"GPL-2.0-with-font-exception":          deprecated,
+	"GPL-3.0":                              osi | deprecated,
+	"GPL-3.0+":                             osi | deprecated,
+	"GPL-3.0-only":                         osi,
+	"GPL-3.0-or-later":                     osi,
+	"GPL-3.0-with-GCC-exception":           osi | deprecated,
+	"GPL-3.0-with-autoconf-exception":      deprecated,
+	"Giftware":                             0,
+	"Glide":                                0,
+	"Glulxe":                               0,
+	"Graphics-Gems":                        0,
+	"HP-1986":                              0,
+	"HP-1989":                              0,
+	"HPND":                                 osi,
+	"HPND-DEC":                             0,
+	"HPND-Fenneberg-Livingston":            0,
+	"HPND-INRIA-IMAG":                      0,
+	"HPND-Kevlin-Henney":                   0,
+	"HPND-MIT-disclaimer":                  0,
+	"HPND-Markus-Kuhn":                     0,
+	"HPND-Pbmplus":                         0,
+	"HPND-UC":                              0,
+	"HPND-doc":                             0,
+	"HPND-doc-sell":                        0,
+	"HPND-export-US":                       0,
+	"HPND-export-US-modify":                0,
+	"HPND-sell-MIT-disclaimer-xserver":     0,
+	"HPND-sell-regexpr":                    0,
+	"HPND-sell-variant":                    0,
+	"HPND-sell-variant-MIT-disclaimer":     0,
+	"HTMLTIDY":                             0,
+	"HaskellReport":                        0,
+	"Hippocratic-2.1":                      0,
+	"IBM-pibs":                             0,
+	"ICU":                                  osi,
+	"IEC-Code-Components-EULA":             0,
+	"IJG":                                  0,
+	"IJG-short":                            0,
+	"IPA":                                  osi,
+	"IPL-1.0":                              osi,
+	"ISC":                                  osi,
+	"ISC-Veillard":                         0,
+	"ImageMagick":                          0,
+	"Imlib2":                               0,
+	"Info-ZIP":                             0,
+	"Inner-Net-2.0":                        0,
+	"Intel":                                osi,
+	"Intel-ACPI":                           0,
+	"Interbase-1.0":                        0,
+	"JPL-image":                            0,
+	"JPNIC":                                0,
+	"JSON":                                 0,
+	"Jam":                                  osi,
+	"JasPer-2.0":                           0,
+	"Kastrup":                              0,
+	"Kazlib":                               0,
+	"Knuth-CTAN":                           0,
+	"LAL-1.2":                              0,
+	"LAL-1.3":                              0,
+	"LGPL-2.0":                             osi | deprecated,
+	"LGPL-2.0+":                            osi | deprecated,
+	"LGPL-2.0-only":                        osi,
+	"LGPL-2.0-or-later":                    osi,
+	"LGPL-2.1":                             osi | deprecated,
+	"LGPL-2.1+":                            osi | deprecated,
+	"LGPL-2.1-only":                        osi,
+	"LGPL-2.1-or-later":                    osi,
+	"LGPL-3.0":                             osi | deprecated,
+	"LGPL-3.0+":                            osi | deprecated,
+	"LGPL-3.0-only":                        osi,
+	"LGPL-3.0-or-later":                    osi,
+	"LGPLLR":                               0,
+	"LOOP":                                 0,
+	"LPD-document":                         0,
+	"LPL-1.0":                              osi,
+	"LPL-1.02":                             osi,
+	"LPPL-1.0":                             0,
+	"LPPL-1.1":                             0,
+	"LPPL-1.2":                             0,
+	"LPPL-1.3a":                            0,
+	"LPPL-1.3c":                            osi,
+	"LZMA-SDK-9.11-to-9.20":                0,
+	"LZMA-SDK-9.22":                        0,
+	"Latex2e":                              0,
+	"Latex2e-translated-notice":            0,
+	"Leptonica":                            0,
+	"LiLiQ-P-1.1":                          osi,
+	"LiLiQ-R-1.1":                          osi,
+	"LiLiQ-Rplus-1.1":                      osi,
+	"Libpng":                               0,
+	"Linux-OpenIB":                         0,
+	"Linux-man-pages-1-para":               0,
+	"Linux-man-pages-copyleft":             0,
+	"Linux-man-pages-copyleft-2-para":      0,
+	"Linux-man-pages-copyleft-var":         0,
+	"Lucida-Bitmap-Fonts":                  0,
+	"MIT":                                  osi,
+	"MIT-0":                                osi,
+	"MIT-CMU":                              0,
+	"MIT-Festival":                         0,
+	"MIT-Modern-Variant":                   osi,
+	"MIT-Wu":                               0,
+	"MIT-advertising":                      0,
+	"MIT-enna":                             0,
+	"MIT-feh":                              0,
+	"MIT-open-group":                       0,
+	"MIT-testregex":                        0,
+	"MITNFA":                               0,
+	"MMIXware":                             0,
+	"MPEG-SSG":                             0,
+	"MPL-1.0":                              osi,
+	"MPL-1.1":                              osi,
+	"MPL-2.0":                              osi,
+	"MPL-2.0-no-copyleft-exception":        osi,
+	"MS-LPL":                               0,
+	"MS-PL":                                osi,
+	"MS-RL":                                osi,
+	"MTLL":                                 0,
+	"Mackerras-3-Clause":                   0,
+	"Mackerras-3-Clause-acknowledgment":    0,
+	"MakeIndex":                            0,
+	"Martin-Birgmeier":                     0,
+	"McPhee-slideshow":                     0,
+	"Minpack":                              0,
+	"MirOS":                                osi,
+	"Motosoto":                             osi,
+	"MulanPSL-1.0":                         0,
+	"MulanPSL-2.0":                         osi,
+	"Multics":                              osi,
+	"Mup":                                  0,
+	"NAIST-2003":                           0,
+	"NASA-1.3":                             osi,
+	"NBPL-1.0":                             0,
+	"NCGL-UK-2.0":                          0,
+	"NCSA":                                 osi,
+	"NGPL":                                 osi,
+	"NICTA-1.0":                            0,
+	"NIST-PD":                              0,
+	"NIST-PD-fallback":                     0,
+	"NIST-Software":                        0,
+	"NLOD-1.0":                             0,
+	"NLOD-2.0":                             0,
+	"NLPL":                                 0,
+	"NOSL":                                 0,
+	"NPL-1.0":                              0,
+	"NPL-1.1":                              0,
+	"NPOSL-3.0":                            osi,
+	"NRL":                                  0,
+	"NTP":                                  osi,
+	"NTP-0":                                0,
+	"Naumen":                               osi,
+	"Net-SNMP":                             0,
+	"NetCDF":                               0,
+	"Newsletr":                             0,
+	"Nokia":                                osi,
+	"Noweb":                                0,
+	"Nunit":                                deprecated,
+	"O-UDA-1.0":                            0,
+	"OCCT-PL":                              0,
+	"OCLC-2.0":                             osi,
+	"ODC-By-1.0":                           0,
+	"ODbL-1.0":                             0,
+	"OFFIS":                                0,
+	"OFL-1.0":                              0,
+	"OFL-1.0-RFN":                          0,
+	"OFL-1.0-no-RFN":                       0,
+	"OFL-1.1":                              osi,
+	"OFL-1.1-RFN":                          osi,
+	"OFL-1.1-no-RFN":                       osi,
+	"OGC-1.0":                              0,
+	"OGDL-Taiwan-1.0":                      0,
+	"OGL-Canada-2.0":                       0,
+	"OGL-UK-1.0":                           0,
+	"OGL-UK-2.0":                           0,
+	"OGL-UK-3.0":                           0,
+	"OGTSL":                                osi,
+	"OLDAP-1.1":                            0,
+	"OLDAP-1.2":                            0,
+	"OLDAP-1.3":                            0,
+	"OLDAP-1.4":                            0,
+	"OLDAP-2.0":                            0,
+	"OLDAP-2.0.1":                          0,
+	"OLDAP-2.1":                            0,
+	"OLDAP-2.2":                            0,
+	"OLDAP-2.2.1":                          0,
+	"OLDAP-2.2.2":                          0,
+	"OLDAP-2.3":                            0,
+	"OLDAP-2.4":                            0,
+	"OLDAP-2.5":                            0,
+	"OLDAP-2.6":                            0,
+	"OLDAP-2.7":                            0,
+	"OLDAP-2.8":                            osi,
+	"OLFL-1.3":                             osi,
+	"OML":                                  0,
+	"OPL-1.0":                              0,
+	"OPL-UK-3.0":                           0,
+	"OPUBL-1.0":                            0,
+	"OSET-PL-2.1":                          osi,
+	"OSL-1.0":                              osi,
+	"OSL-1.1":                              0,
+	"OSL-2.0":                              osi,
+	"OSL-2.1":                              osi,
+	"OSL-3.0":                              osi,
+	"OpenPBS-2.3":                          0,
+	"OpenSSL":                              0,
+	"OpenSSL-standalone":                   0,
+	"OpenVision":                           0,
+	"PADL":                                 0,
+	"PDDL-1.0":                             0,
+	"PHP-3.0":                              osi,
+	"PHP-3.01":                             osi,
+	"PSF-2.0":                              0,
+	"Parity-6.0.0":                         0,
+	"Parity-7.0.0":                         0,
+	"Pixar":                                0,
+	"Plexus":                               0,
+	"PolyForm-Noncommercial-1.0.0":         0,
+	"PolyForm-Small-Business-1.0.0":        0,
+	"PostgreSQL":                           osi,
+	"Python-2.0":                           osi,
+	"Python-2.0.1":                         0,
+	"QPL-1.0":                              osi,
+	"QPL-1.0-INRIA-2004":                   0,
+	"Qhull":                                0,
+	"RHeCos-1.1":                           0,
+	"RPL-1.1":                              osi,
+	"RPL-1.5":                              osi,
+	"RPSL-1.0":                             osi,
+	"RSA-MD":                               0,
+	"RSCPL":                                osi,
+	"Rdisc":                                0,
+	"Ruby":                                 0,
+	"SAX-PD":                               0,
+	"SAX-PD-2.0":                           0,
+	"SCEA":                                 0,
+	"SGI-B-1.0":                            0,
+	"SGI-B-1.1":                            0,
+	"SGI-B-2.0":                            0,
+	"SGI-OpenGL":                           0,
+	"SGP4":                                 0,
+	"SHL-0.5":                              0,
+	"SHL-0.51":                             0,
+	"SISSL":                                osi,
+	"SISSL-1.2":                            0,
+	"SL":                                   0,
+	"SMLNJ":                                0,
+	"SMPPL":                                0,
+	"SNIA":                                 0,
+	"SPL-1.0":                              osi,
+	"SSH-OpenSSH":                          0,
+	"SSH-short":                            0,
+	"SSLeay-standalone":                    0,
+	"SSPL-1.0":                             0,
+	"SWL":                                  0,
+	"Saxpath":                              0,
+	"SchemeReport":                         0,
+	"Sendmail":                             0,
+	"Sendmail-8.23":                        0,
+	"SimPL-2.0":                            osi,
+	"Sleepycat":                            osi,
+	"Soundex":                              0,
+	"Spencer-86":                           0,
+	"Spencer-94":                           0,
+	"Spencer-99":                           0,
+	"StandardML-NJ":                        deprecated,
+	"SugarCRM-1.1.3":                       0,
+	"Sun-PPP":                              0,
+	"SunPro":                               0,
+	"Symlinks":                             0,
+	"TAPR-OHL-1.0":                         0,
+	"TCL":                                  0,
+	"TCP-wrappers":                         0,
+	"TGPPL-1.0":                            0,
+	"TMate":                                0,
+	"TORQUE-1.1":                           0,
+	"TOSL":                                 0,
+	"TPDL":                                 0,
+	"TPL-1.0":                              0,
+	"TTWL":                                 0,
+	"TTYP0":                                0,
+	"TU-Berlin-1.0":                        0,
+	"TU-Berlin-2.0":                        0,
+	"TermReadKey":                          0,
+	"UCAR":                                 0,
+	"UCL-1.0":                              osi,
+	"UMich-Merit":                          0,
+	"UPL-1.0":                              osi,
+	"URT-RLE":                              0,
+	"Unicode-3.0":                          osi,
+	"Unicode-DFS-2015":                     0,
+	"Unicode-DFS-2016":                     osi,
+	"Unicode-TOU":                          0,
+	"UnixCrypt":                            0,
+	"Unlicense":                            osi,
+	"VOSTROM":                              0,
+	"VSL-1.0":                              osi,
+	"Vim":                                  0,
+	"W3C":                                  osi,
+	"W3C-19980720":                         0,
+	"W3C-20150513":                         0,
+	"WTFPL":                                0,
+	"Watcom-1.0":                           osi,
+	"Widget-Workshop":                      0,
+	"Wsuipa":                               0,
+	"X11":                                  0,
+	"X11-distribute-modifications-variant": 0,
+	"XFree86-1.1":                          0,
+	"XSkat":                                0,
+	"Xdebug-1.03":                          0,
+	"Xerox":                                0,
+	"Xfig":                                 0,
+	"Xnet":                                 osi,
+	"YPL-1.0":                              0,
+	"YPL-1.1":                              0,
+	"ZPL-1.1":                              0,
+	"ZPL-2.0":                              osi,
+	"ZPL-2.1":                              osi,
+	"Zed":                                  0,
+	"Zeeff":                                0,
+	"Zend-2.0":                             0,
+	"Zimbra-1.3":                           0,
+	"Zimbra-1.4":                           0,
+	"Zlib":                                 osi,
+	"bcrypt-Solar-Designer":                0,
+	"blessing":                             0,
+	"bzip2-1.0.5":                          deprecated,
+	"bzip2-1.0.6":                          0,
+	"check-cvs":                            0,
+	"checkmk":                              0,
+	"copyleft-next-0.3.0":                  0,
+	"copyleft-next-0.3.1":                  0,
+	"curl":                                 0,
+	"diffmark":                             0,
+	"dtoa":                                 0,
+	"dvipdfm":                              0,
+	"eCos-2.0":                             deprecated,
+	"eGenix":                               0,
+	"etalab-2.0":                           0,
+	"fwlw":                                 0,
+	"gSOAP-1.3b":                           0,
+	"gnuplot":                              0,
+	"gtkbook":                              0,
+	"hdparm":                               0,
+	"iMatix":                               0,
+	"libpng-2.0":                           0,
+	"libselinux-1.0":                       0,
+	"libtiff":                              0,
+	"libutil-David-Nugent":                 0,
+	"lsof":                                 0,
+	"magaz":                                0,
+	"mailprio":                             0,
+	"metamail":                             0,
+	"mpi-permissive":                       0,
+	"mpich2":                               0,
+	"mplus":                                0,
+	"pnmstitch":                            0,
+	"psfrag":                               0,
+	"psutils":                              0,
+	"python-ldap":                          0,
+	"radvd":                                0,
+	"snprintf":                             0,
+	"softSurfer":                           0,
+	"ssh-keyscan":                          0,
+	"swrule":                               0,
+	"ulem":                                 0,
+	"w3m":                                  0,
+	"wxWindows":                            osi | deprecated,
+	"xinetd":                               0,
+	"xkeyboard-config-Zinoviev":            0,
+	"xlock":                                0,
+	"xpp":                                  0,
+	"zlib-acknowledgement":                 0,
+}
@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Duncaen/go-xbps/license"
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+// MaxShortDesc is the maximum length of short descriptions in characters
+const MaxShortDesc = 72
+
+// DefaultChecks returns the default checks
+func DefaultChecks() []Check {
+	return []Check{
+		{Name: "pkgver", Severity: SeverityError, Run: checkPkgVer},
+		{Name: "license", Severity: SeverityError, Run: checkLicense},
+		{Name: "license-id", Severity: SeverityWarning, Run: checkLicenseID},
+		{Name: "homepage", Severity: SeverityWarning, Run: checkHomepage},
+		{Name: "maintainer", Severity: SeverityWarning, Run: checkMaintainer},
+		{Name: "short_desc", Severity: SeverityWarning, Run: checkShortDesc},
+		{Name: "run_depends", Severity: SeverityError, Run: checkRunDepends},
+		{Name: "conflicts", Severity: SeverityError, Run: checkConflicts},
+		{Name: "filename-size", Severity: SeverityError, Run: checkFilenameSize},
+	}
+}
+
+func checkPkgVer(c *Context, pkg *repo.Package) []string {
+	pv, err := pkgver.Parse(pkg.PkgVer)
+	if err != nil || pv.Version == "" || pv.Name == "" {
+		return []string{fmt.Sprintf("invalid pkgver %q", pkg.PkgVer)}
+	}
+	return nil
+}
+
+func checkLicense(c *Context, pkg *repo.Package) []string {
+	if pkg.License == "" {
+		return []string{"missing license"}
+	}
+	if _, err := license.Parse(pkg.License); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// checkLicenseID reports license identifiers that are not in the SPDX
+// license list, only a warning as the list may lag behind new licenses.
+func checkLicenseID(c *Context, pkg *repo.Package) []string {
+	e, err := license.Parse(pkg.License)
+	if err != nil {
+		// reported by the license check
+		return nil
+	}
+	var res []string
+	for _, l := range license.Licenses(e) {
+		if !l.Known() {
+			res = append(res, fmt.Sprintf("unknown SPDX license identifier %q", l.ID))
+		}
+	}
+	return res
+}
+
+func checkHomepage(c *Context, pkg *repo.Package) []string {
+	if pkg.Homepage == "" {
+		return []string{"missing homepage"}
+	}
+	u, err := url.Parse(pkg.Homepage)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ftp") || u.Host == "" {
+		return []string{fmt.Sprintf("malformed homepage URL %q", pkg.Homepage)}
+	}
+	return nil
+}
+
+func checkMaintainer(c *Context, pkg *repo.Package) []string {
+	if _, err := mail.ParseAddress(pkg.Maintainer); err != nil {
+		return []string{fmt.Sprintf("maintainer %q has no email address", pkg.Maintainer)}
+	}
+	return nil
+}
+
+func checkShortDesc(c *Context, pkg *repo.Package) []string {
+	var res []string
+	switch {
+	case pkg.ShortDesc == "":
+		return []string{"missing short_desc"}
+	case utf8.RuneCountInString(pkg.ShortDesc) > MaxShortDesc:
+		res = append(res, fmt.Sprintf("longer than %d characters", MaxShortDesc))
+	}
+	if strings.HasSuffix(pkg.ShortDesc, ".") {
+		res = append(res, "ends with a period")
+	}
+	return res
+}
+
+func checkRunDepends(c *Context, pkg *repo.Package) []string {
+	var res []string
+	for _, dep := range pkg.RunDepends {
+		pv, err := pkgver.Parse(dep)
+		if err != nil {
+			res = append(res, fmt.Sprintf("malformed dependency %q", dep))
+			continue
+		}
+		if !c.Exists(pv.Name) {
+			res = append(res, fmt.Sprintf("dependency %q does not exist", dep))
+		}
+	}
+	return res
+}
+
+func checkConflicts(c *Context, pkg *repo.Package) []string {
+	var res []string
+	for _, pattern := range pkg.Conflicts {
+		if pkgver.Match(pkg.PkgVer, pattern) {
+			res = append(res, fmt.Sprintf("conflicts with itself %q", pattern))
+		}
+	}
+	return res
+}
+
+func checkFilenameSize(c *Context, pkg *repo.Package) []string {
+	// only repository indexes contain the file size
+	if c.Repository != nil && pkg.FilenameSize == 0 {
+		return []string{"filename-size is zero"}
+	}
+	return nil
+}
+
+// PolicyCheck returns a check for licenses rejected by the policy
+func PolicyCheck(p *license.Policy) Check {
+	return Check{Name: "license-policy", Severity: SeverityError, Run: func(c *Context, pkg *repo.Package) []string {
+		e, err := license.Parse(pkg.License)
+		if err != nil {
+			// reported by the license check
+			return nil
+		}
+		var res []string
+		for _, l := range p.Check(e) {
+			res = append(res, fmt.Sprintf("license %q is rejected by policy", l))
+		}
+		return res
+	}}
+}
@@ -0,0 +1,175 @@
+// Package lint implements checking package metadata of repositories and
+// binary packages for common mistakes.
+//
+// A Linter runs a list of checks over each package and returns findings
+// that can be encoded as JSON, checks can be added or removed by modifying
+// Linter.Checks.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+// Severity is the severity of a finding
+type Severity string
+
+const (
+	// SeverityError is a finding that should prevent publishing the package
+	SeverityError Severity = "error"
+	// SeverityWarning is a finding that should be fixed
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem found by a check
+type Finding struct {
+	// PkgVer is the package the problem was found in
+	PkgVer string `json:"pkgver"`
+	// Check is the name of the check that found the problem
+	Check string `json:"check"`
+	// Severity is the severity of the check
+	Severity Severity `json:"severity"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", f.PkgVer, f.Severity, f.Check, f.Message)
+}
+
+// Check is a lint check
+type Check struct {
+	// Name is the name of the check
+	Name string
+	// Severity is the severity of findings of the check
+	Severity Severity
+	// Run returns the problems of the package
+	Run func(c *Context, pkg *repo.Package) []string
+}
+
+// Context is the set of packages dependencies are resolved against
+type Context struct {
+	// Repository is the linted repository, nil for single packages
+	Repository *repo.Repository
+	names      map[string]bool
+}
+
+// Exists returns true if a package or virtual package with the name
+// exists, it is always true if the package set is not known.
+func (c *Context) Exists(name string) bool {
+	return c.names == nil || c.names[name]
+}
+
+func (c *Context) add(r *repo.Repository) {
+	if c.names == nil {
+		c.names = make(map[string]bool)
+	}
+	for name, pkg := range r.Index {
+		c.names[name] = true
+		for _, p := range pkg.Provides {
+			if pv, err := pkgver.Parse(p); err == nil {
+				c.names[pv.Name] = true
+			}
+		}
+	}
+}
+
+// Linter runs checks over packages
+type Linter struct {
+	// Checks are the checks to run
+	Checks []Check
+	// Deps are additional repositories dependencies are resolved against
+	Deps []*repo.Repository
+}
+
+// New returns a linter with the default checks
+func New() *Linter {
+	return &Linter{Checks: DefaultChecks()}
+}
+
+// Disable removes the checks with the names
+func (l *Linter) Disable(names ...string) {
+	checks := l.Checks[:0]
+	for _, c := range l.Checks {
+		disabled := false
+		for _, name := range names {
+			disabled = disabled || c.Name == name
+		}
+		if !disabled {
+			checks = append(checks, c)
+		}
+	}
+	l.Checks = checks
+}
+
+func (l *Linter) context(r *repo.Repository) *Context {
+	c := &Context{Repository: r}
+	if r != nil {
+		c.add(r)
+	}
+	for _, d := range l.Deps {
+		c.add(d)
+	}
+	return c
+}
+
+func (l *Linter) run(c *Context, pkg *repo.Package) []Finding {
+	var res []Finding
+	for _, check := range l.Checks {
+		for _, msg := range check.Run(c, pkg) {
+			res = append(res, Finding{
+				PkgVer:   pkg.PkgVer,
+				Check:    check.Name,
+				Severity: check.Severity,
+				Message:  msg,
+			})
+		}
+	}
+	return res
+}
+
+// Repository returns the findings of all packages in the repository index
+// ordered by package name.
+func (l *Linter) Repository(r *repo.Repository) []Finding {
+	c := l.context(r)
+	names := make([]string, 0, len(r.Index))
+	for name := range r.Index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var res []Finding
+	for _, name := range names {
+		pkg := r.Index[name]
+		res = append(res, l.run(c, &pkg)...)
+	}
+	return res
+}
+
+// Package returns the findings of a single package, dependencies are only
+// checked if the linter has dependency repositories.
+func (l *Linter) Package(pkg *repo.Package) []Finding {
+	return l.run(l.context(nil), pkg)
+}
+
+// File returns the findings of the binary package at path
+func (l *Linter) File(path string) ([]Finding, error) {
+	meta, err := binpkg.OpenMetadata(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.Package(&meta.Props), nil
+}
+
+// Errors returns true if any finding is an error
+func Errors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
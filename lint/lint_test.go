@@ -0,0 +1,85 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/license"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func good(pkgver string) repo.Package {
+	return repo.Package{
+		PkgVer:       pkgver,
+		License:      "GPL-2.0-or-later, custom:Hybrid",
+		Homepage:     "https://example.org/",
+		Maintainer:   "Foo Bar <foo@example.org>",
+		ShortDesc:    "Example package",
+		FilenameSize: 1024,
+	}
+}
+
+func TestRepository(t *testing.T) {
+	foo := good("foo-1.0_1")
+	foo.RunDepends = []string{"libbar>=1.0_1", "virt>=0", "missing>=1.0_1"}
+	foo.Conflicts = []string{"foo<2.0_1", "oldfoo"}
+	bar := good("libbar-1.0_1")
+	bar.License = "MIT OR Apache-2.0, Frobnicate-1.0"
+	bar.Homepage = "example.org"
+	bar.Maintainer = "Orphaned"
+	bar.ShortDesc = "Library that does a lot of things and has a description that is way too long."
+	bar.FilenameSize = 0
+	bar.Provides = []string{"virt-1.0_1"}
+	baz := good("baz")
+	r := &repo.Repository{Index: map[string]repo.Package{"foo": foo, "libbar": bar, "baz": baz}}
+
+	var got []string
+	for _, f := range New().Repository(r) {
+		got = append(got, f.String())
+	}
+	expect := []string{
+		"baz: error: pkgver: invalid pkgver \"baz\"",
+		"foo-1.0_1: error: run_depends: dependency \"missing>=1.0_1\" does not exist",
+		"foo-1.0_1: error: conflicts: conflicts with itself \"foo<2.0_1\"",
+		"libbar-1.0_1: warning: license-id: unknown SPDX license identifier \"Frobnicate-1.0\"",
+		"libbar-1.0_1: warning: homepage: malformed homepage URL \"example.org\"",
+		"libbar-1.0_1: warning: maintainer: maintainer \"Orphaned\" has no email address",
+		"libbar-1.0_1: warning: short_desc: longer than 72 characters",
+		"libbar-1.0_1: warning: short_desc: ends with a period",
+		"libbar-1.0_1: error: filename-size: filename-size is zero",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected findings:\n%q\ngot:\n%q", expect, got)
+	}
+}
+
+func TestPackage(t *testing.T) {
+	pkg := good("foo-1.0_1")
+	pkg.RunDepends = []string{"libbar>=1.0_1"}
+	pkg.FilenameSize = 0
+	// the length is counted in characters, not bytes
+	pkg.ShortDesc = "Übersetzungswerkzeug für Ärzte, Übungsleiter und Ökotrophologen – äöü äö"
+	l := New()
+	if f := l.Package(&pkg); len(f) != 0 {
+		t.Fatalf("expected no findings, got %v", f)
+	}
+	l.Deps = []*repo.Repository{{Index: map[string]repo.Package{"glibc": good("glibc-2.36_1")}}}
+	f := l.Package(&pkg)
+	if len(f) != 1 || f[0].Check != "run_depends" || !Errors(f) {
+		t.Fatalf("expected missing dependency, got %v", f)
+	}
+	l.Disable("run_depends")
+	if f := l.Package(&pkg); len(f) != 0 {
+		t.Fatalf("expected no findings with disabled check, got %v", f)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	pkg := good("foo-1.0_1")
+	pkg.License = "AGPL-3.0-only, MIT"
+	l := &Linter{Checks: []Check{PolicyCheck(&license.Policy{Deny: []string{"AGPL-*"}})}}
+	f := l.Package(&pkg)
+	if len(f) != 1 || f[0].Message != "license \"AGPL-3.0-only\" is rejected by policy" {
+		t.Fatalf("unexpected findings %v", f)
+	}
+}
@@ -0,0 +1,203 @@
+package pkgdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/pkgver"
+)
+
+// ProblemKind is the kind of a problem found by Check
+type ProblemKind string
+
+const (
+	// ProblemState is a package that is not fully installed
+	ProblemState ProblemKind = "state"
+	// ProblemMetafile is a package with a missing or invalid file list
+	ProblemMetafile ProblemKind = "metafile"
+	// ProblemMissingFile is a file of the package that does not exist
+	ProblemMissingFile ProblemKind = "missing-file"
+	// ProblemHashMismatch is a file that does not match its sha256 hash
+	ProblemHashMismatch ProblemKind = "hash-mismatch"
+	// ProblemSymlink is a symbolic link with a different or no target
+	ProblemSymlink ProblemKind = "symlink"
+	// ProblemRunDepends is a run dependency that is not installed
+	ProblemRunDepends ProblemKind = "run-depends"
+	// ProblemShlib is a shared library requirement no package provides
+	ProblemShlib ProblemKind = "shlib"
+)
+
+// Problem is an inconsistency of an installed package
+type Problem struct {
+	// PkgName is the name of the package
+	PkgName string `json:"pkgname"`
+	// PkgVer is the installed package version
+	PkgVer string `json:"pkgver"`
+	// Kind is the kind of the problem
+	Kind ProblemKind `json:"kind"`
+	// Path is the affected path if the problem is related to a file
+	Path string `json:"path,omitempty"`
+	// Detail describes the problem
+	Detail string `json:"detail,omitempty"`
+}
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("%s: %s", p.PkgVer, p.Kind)
+	if p.Path != "" {
+		s += ": " + p.Path
+	}
+	if p.Detail != "" {
+		s += ": " + p.Detail
+	}
+	return s
+}
+
+// Check verifies all installed packages like xbps-pkgdb -a and returns
+// the problems sorted by package name.
+func (db *DB) Check() []Problem {
+	shlibs := db.shlibs()
+	var res []Problem
+	for _, name := range db.Names() {
+		res = append(res, db.check(name, shlibs)...)
+	}
+	return res
+}
+
+// CheckPackage verifies the installed package
+func (db *DB) CheckPackage(name string) ([]Problem, error) {
+	if _, ok := db.Packages[name]; !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return db.check(name, db.shlibs()), nil
+}
+
+// shlibs returns the shared libraries provided by the installed packages
+func (db *DB) shlibs() map[string]bool {
+	shlibs := make(map[string]bool)
+	for _, pkg := range db.Packages {
+		for _, shlib := range pkg.ShlibProvides {
+			shlibs[shlib] = true
+		}
+	}
+	return shlibs
+}
+
+func (db *DB) check(name string, shlibs map[string]bool) []Problem {
+	pkg := db.Packages[name]
+	var res []Problem
+	report := func(kind ProblemKind, path, detail string) {
+		res = append(res, Problem{PkgName: name, PkgVer: pkg.PkgVer, Kind: kind, Path: path, Detail: detail})
+	}
+	if pkg.State != StateInstalled {
+		report(ProblemState, "", pkg.State)
+	}
+	if files, err := db.Files(name); err != nil {
+		// packages without files, like meta packages, have no file list
+		if !errors.Is(err, fs.ErrNotExist) {
+			report(ProblemMetafile, db.FilesPath(name), err.Error())
+		}
+	} else {
+		for _, e := range files.Files {
+			if err := db.checkHash(e.File, e.SHA256); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					report(ProblemMissingFile, e.File, "")
+				} else {
+					report(ProblemHashMismatch, e.File, err.Error())
+				}
+			}
+		}
+		for _, e := range files.ConfFiles {
+			// configuration files may be modified
+			if _, err := os.Lstat(db.path(e.File)); err != nil {
+				report(ProblemMissingFile, e.File, "")
+			}
+		}
+		for _, e := range files.Links {
+			target, err := os.Readlink(db.path(e.File))
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				report(ProblemMissingFile, e.File, "")
+			case err != nil:
+				report(ProblemSymlink, e.File, "not a symbolic link")
+			case e.Target != "" && !sameTarget(e.File, target, e.Target):
+				report(ProblemSymlink, e.File, fmt.Sprintf("target %s does not match %s", target, e.Target))
+			}
+		}
+	}
+	for _, dep := range pkg.RunDepends {
+		if !db.satisfied(dep) {
+			report(ProblemRunDepends, "", dep)
+		}
+	}
+	for _, shlib := range pkg.ShlibRequires {
+		if !shlibs[shlib] {
+			report(ProblemShlib, "", shlib)
+		}
+	}
+	return res
+}
+
+// path returns the path of the package file in the root directory
+func (db *DB) path(file string) string {
+	return filepath.Join(db.RootDir, file)
+}
+
+// checkHash compares the sha256 hash of the file, files without hash
+// in the file list are only checked for existence.
+func (db *DB) checkHash(file, hash string) error {
+	f, err := os.Open(db.path(file))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if hash == "" {
+		return nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, hash) {
+		return fmt.Errorf("sha256 %s does not match %s", sum, hash)
+	}
+	return nil
+}
+
+// sameTarget compares symbolic link targets relative to the link
+func sameTarget(link, a, b string) bool {
+	if a == b {
+		return true
+	}
+	resolve := func(target string) string {
+		if filepath.IsAbs(target) {
+			return filepath.Clean(target)
+		}
+		return filepath.Join(filepath.Dir(link), target)
+	}
+	return resolve(a) == resolve(b)
+}
+
+// satisfied returns true if an installed package or virtual package matches the pattern
+func (db *DB) satisfied(pattern string) bool {
+	for _, pkg := range db.Packages {
+		if pkg.State != StateInstalled && pkg.State != StateUnpacked {
+			continue
+		}
+		if pkgver.Match(pkg.PkgVer, pattern) {
+			return true
+		}
+		for _, virtual := range pkg.Provides {
+			if pkgver.Match(virtual, pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package pkgdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/repo"
+	"howett.net/plist"
+)
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	buf, err := plist.MarshalIndent(v, plist.XMLFormat, "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+func writeFile(t *testing.T, root, path, content string) string {
+	t.Helper()
+	path = filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	foo := writeFile(t, root, "usr/bin/foo", "foo")
+	bar := writeFile(t, root, "usr/bin/bar", "bar")
+	writeFile(t, root, "usr/bin/bar", "modified bar")
+	writeFile(t, root, "etc/foo.conf", "modified config")
+	if err := os.Symlink("foo", filepath.Join(root, "usr/bin/foolink")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("bar", filepath.Join(root, "usr/bin/badlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs := map[string]Package{
+		"foo": {
+			Package: repo.Package{
+				PkgVer:        "foo-1.0_1",
+				RunDepends:    []string{"libfoo>=1.0_1", "virtual-foo>=0", "missing>=1.0_1"},
+				ShlibRequires: []string{"libfoo.so.1", "libmissing.so.1"},
+			},
+			State: StateInstalled,
+		},
+		"libfoo": {
+			Package: repo.Package{
+				PkgVer:        "libfoo-1.2_1",
+				ShlibProvides: []string{"libfoo.so.1"},
+				Provides:      []string{"virtual-foo-1.0_1"},
+			},
+			State: StateInstalled,
+		},
+		"broken": {
+			Package: repo.Package{PkgVer: "broken-1.0_1"},
+			State:   StateHalfUnpacked,
+		},
+	}
+	files := binpkg.Files{
+		Files: []binpkg.Entry{
+			{File: "/usr/bin/foo", SHA256: foo},
+			{File: "/usr/bin/bar", SHA256: bar},
+			{File: "/usr/bin/gone", SHA256: foo},
+		},
+		ConfFiles: []binpkg.Entry{{File: "/etc/foo.conf", SHA256: foo}},
+		Links: []binpkg.Entry{
+			{File: "/usr/bin/foolink", Target: "/usr/bin/foo"},
+			{File: "/usr/bin/badlink", Target: "foo"},
+		},
+	}
+	writeDB(t, root, marshal(t, pkgs), map[string]string{"foo": marshal(t, files)})
+
+	db, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Problem{
+		{PkgName: "broken", PkgVer: "broken-1.0_1", Kind: ProblemState, Detail: StateHalfUnpacked},
+		{PkgName: "foo", PkgVer: "foo-1.0_1", Kind: ProblemHashMismatch, Path: "/usr/bin/bar"},
+		{PkgName: "foo", PkgVer: "foo-1.0_1", Kind: ProblemMissingFile, Path: "/usr/bin/gone"},
+		{PkgName: "foo", PkgVer: "foo-1.0_1", Kind: ProblemSymlink, Path: "/usr/bin/badlink"},
+		{PkgName: "foo", PkgVer: "foo-1.0_1", Kind: ProblemRunDepends, Detail: "missing>=1.0_1"},
+		{PkgName: "foo", PkgVer: "foo-1.0_1", Kind: ProblemShlib, Detail: "libmissing.so.1"},
+	}
+	problems := db.Check()
+	for i := range problems {
+		// details of file problems are messages
+		if problems[i].Path != "" {
+			problems[i].Detail = ""
+		}
+	}
+	if !reflect.DeepEqual(problems, expect) {
+		t.Fatalf("expected problems:\n%v\ngot:\n%v", expect, problems)
+	}
+
+	if _, err := db.CheckPackage("nonexistent"); err == nil {
+		t.Fatal("expected error for missing package")
+	}
+	if problems, err := db.CheckPackage("libfoo"); err != nil || len(problems) != 0 {
+		t.Fatalf("unexpected problems for libfoo: %v, %v", problems, err)
+	}
+}
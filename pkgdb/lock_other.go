@@ -0,0 +1,20 @@
+//go:build !unix
+
+package pkgdb
+
+import (
+	"errors"
+)
+
+// Lock is a lock on the package database
+type Lock struct{}
+
+// LockMetaDir is not supported on this platform
+func LockMetaDir(metadir string) (*Lock, error) {
+	return nil, errors.New("package database locking is not supported on this platform")
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package pkgdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Lock is a lock on the package database
+type Lock struct {
+	f *os.File
+}
+
+// LockMetaDir acquires the package database lock of the metadata directory.
+//
+// Like xbps it uses a POSIX record lock on the lock file in the metadata
+// directory and does not wait, ErrLocked is returned if another process
+// holds the lock.
+func LockMetaDir(metadir string) (*Lock, error) {
+	path := filepath.Join(metadir, "lock")
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o664)
+	if err != nil {
+		return nil, err
+	}
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: 0}
+	if err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EACCES) {
+			return nil, fmt.Errorf("%s: %w", path, ErrLocked)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	return l.f.Close()
+}
@@ -0,0 +1,250 @@
+package pkgdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"howett.net/plist"
+)
+
+var (
+	// ErrLocked is returned if another process holds the package database lock
+	ErrLocked = errors.New("package database is locked")
+	// ErrNotInstalled is returned if no installed package matches a pattern
+	ErrNotInstalled = errors.New("package is not installed")
+	// ErrNotRead is returned when writing a package database that was not read
+	ErrNotRead = errors.New("package database was not read")
+)
+
+// Mode is a package mode change like xbps-pkgdb -m
+type Mode string
+
+const (
+	// ModeHold holds the package at its version during updates
+	ModeHold Mode = "hold"
+	// ModeUnhold allows updates of the package
+	ModeUnhold Mode = "unhold"
+	// ModeRepoLock only allows updates from the repository the package was installed from
+	ModeRepoLock Mode = "repolock"
+	// ModeRepoUnlock allows updates from any repository
+	ModeRepoUnlock Mode = "repounlock"
+	// ModeAuto marks the package as automatically installed
+	ModeAuto Mode = "auto"
+	// ModeManual marks the package as manually installed
+	ModeManual Mode = "manual"
+)
+
+// Match returns the sorted names of installed packages matching the
+// glob patterns, ErrNotInstalled is returned if a pattern matches nothing.
+func (db *DB) Match(patterns ...string) ([]string, error) {
+	matched := make(map[string]bool)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+		found := false
+		for name := range db.Packages {
+			if ok, _ := path.Match(pattern, name); ok {
+				matched[name] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s: %w", pattern, ErrNotInstalled)
+		}
+	}
+	names := make([]string, 0, len(matched))
+	for name := range matched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetMode changes the mode of the installed packages matching the glob
+// patterns and returns their names, the changes are not written until
+// Write is called.
+func (db *DB) SetMode(mode Mode, patterns ...string) ([]string, error) {
+	var key string
+	var value bool
+	switch mode {
+	case ModeHold, ModeUnhold:
+		key, value = "hold", mode == ModeHold
+	case ModeRepoLock, ModeRepoUnlock:
+		key, value = "repolock", mode == ModeRepoLock
+	case ModeAuto, ModeManual:
+		key, value = "automatic-install", mode == ModeAuto
+	default:
+		return nil, fmt.Errorf("invalid mode: %s", mode)
+	}
+	names, err := db.Match(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		pkg := db.Packages[name]
+		switch key {
+		case "hold":
+			pkg.Hold = value
+		case "repolock":
+			pkg.RepoLock = value
+		case "automatic-install":
+			pkg.AutomaticInstall = value
+		}
+		db.Packages[name] = pkg
+
+		d, ok := db.raw[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		// like xbps-pkgdb, unhold and repounlock remove the key
+		// while manual sets automatic-install to false
+		if value || key == "automatic-install" {
+			d[key] = value
+		} else {
+			delete(d, key)
+		}
+	}
+	return names, nil
+}
+
+// Write atomically replaces the package database plist with the
+// current package database, changes to Packages are merged into the
+// package database that was read and keys unknown to Package are
+// preserved. ErrNotRead is returned if the package database was not
+// read with Open or OpenMetaDir.
+//
+// The caller should hold the package database lock.
+func (db *DB) Write() error {
+	if db.raw == nil {
+		return ErrNotRead
+	}
+	if err := db.merge(); err != nil {
+		return err
+	}
+	buf, err := plist.MarshalIndent(db.raw, plist.XMLFormat, "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(db.MetaDir, ".pkgdb-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), db.Path())
+}
+
+// packageKeys are the plist keys of the fields of Package
+var packageKeys = plistKeys(reflect.TypeOf(Package{}))
+
+func plistKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			keys = append(keys, plistKeys(f.Type)...)
+			continue
+		}
+		if key, _, _ := strings.Cut(f.Tag.Get("plist"), ","); key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// merge updates the undecoded package database with Packages
+func (db *DB) merge() error {
+	for name := range db.raw {
+		if _, ok := db.Packages[name]; !ok && !strings.HasPrefix(name, "_XBPS_") {
+			delete(db.raw, name)
+		}
+	}
+	for name, pkg := range db.Packages {
+		buf, err := plist.Marshal(pkg, plist.BinaryFormat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		var enc map[string]any
+		if _, err := plist.Unmarshal(buf, &enc); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		d, ok := db.raw[name].(map[string]any)
+		if !ok {
+			db.raw[name] = enc
+			continue
+		}
+		for _, key := range packageKeys {
+			if v, ok := enc[key]; ok {
+				d[key] = v
+			} else if v, ok := d[key]; ok && !empty(v) {
+				// keep explicit zero values like a false automatic-install
+				delete(d, key)
+			}
+		}
+	}
+	return nil
+}
+
+// empty returns true if v is the zero value of a plist type
+func empty(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case uint64:
+		return v == 0
+	case int64:
+		return v == 0
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// SetMode locks the package database of the root directory, changes the
+// mode of the installed packages matching the glob patterns and writes
+// the package database.
+func SetMode(rootdir string, mode Mode, patterns ...string) ([]string, error) {
+	metadir := filepath.Join(rootdir, MetaDir)
+	lock, err := LockMetaDir(metadir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	db, err := OpenMetaDir(rootdir, metadir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := db.SetMode(mode, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Write(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
@@ -0,0 +1,220 @@
+package pkgdb
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/repo"
+	"howett.net/plist"
+)
+
+const modePkgDB = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>_XBPS_ALTERNATIVES_</key>
+	<dict>
+		<key>sh</key>
+		<array><string>dash</string></array>
+	</dict>
+	<key>linux5.10</key>
+	<dict>
+		<key>pkgver</key>
+		<string>linux5.10-5.10.1_1</string>
+		<key>state</key>
+		<string>installed</string>
+		<key>automatic-install</key>
+		<true/>
+		<key>unknown-key</key>
+		<string>preserved</string>
+	</dict>
+	<key>linux5.15</key>
+	<dict>
+		<key>pkgver</key>
+		<string>linux5.15-5.15.1_1</string>
+		<key>state</key>
+		<string>installed</string>
+		<key>hold</key>
+		<true/>
+	</dict>
+	<key>foo</key>
+	<dict>
+		<key>pkgver</key>
+		<string>foo-1.0_1</string>
+		<key>state</key>
+		<string>installed</string>
+	</dict>
+</dict>
+</plist>`
+
+func TestSetMode(t *testing.T) {
+	root := t.TempDir()
+	writeDB(t, root, modePkgDB, nil)
+
+	names, err := SetMode(root, ModeHold, "linux*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"linux5.10", "linux5.15"}) {
+		t.Fatalf("unexpected packages %v", names)
+	}
+	if _, err := SetMode(root, ModeManual, "linux5.10"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetMode(root, ModeRepoLock, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetMode(root, ModeUnhold, "linux5.15"); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg := db.Packages["linux5.10"]; !pkg.Hold || pkg.AutomaticInstall {
+		t.Errorf("unexpected linux5.10 %+v", pkg)
+	}
+	if pkg := db.Packages["linux5.15"]; pkg.Hold {
+		t.Errorf("unexpected linux5.15 %+v", pkg)
+	}
+	if pkg := db.Packages["foo"]; !pkg.RepoLock {
+		t.Errorf("unexpected foo %+v", pkg)
+	}
+
+	var raw map[string]map[string]any
+	buf, err := os.ReadFile(db.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plist.Unmarshal(buf, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["linux5.10"]["unknown-key"] != "preserved" {
+		t.Errorf("unknown key was not preserved: %v", raw["linux5.10"])
+	}
+	if _, ok := raw["linux5.15"]["hold"]; ok {
+		t.Errorf("unhold did not remove hold: %v", raw["linux5.15"])
+	}
+	if _, ok := raw["_XBPS_ALTERNATIVES_"]; !ok {
+		t.Errorf("alternatives were not preserved")
+	}
+
+	if _, err := SetMode(root, ModeHold, "bar*"); !errors.Is(err, ErrNotInstalled) {
+		t.Fatalf("expected ErrNotInstalled, got %v", err)
+	}
+	if _, err := SetMode(root, "bogus", "foo"); err == nil {
+		t.Fatal("expected error for invalid mode")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	root := t.TempDir()
+	writeDB(t, root, modePkgDB, nil)
+	db, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := db.Packages["linux5.10"]
+	pkg.PkgVer = "linux5.10-5.10.2_1"
+	pkg.AutomaticInstall = false
+	db.Packages["linux5.10"] = pkg
+	delete(db.Packages, "linux5.15")
+	db.Packages["bar"] = Package{Package: repo.Package{PkgVer: "bar-1.0_1"}, State: StateInstalled}
+	if err := db.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := db.Names(); !reflect.DeepEqual(names, []string{"bar", "foo", "linux5.10"}) {
+		t.Fatalf("unexpected packages %v", names)
+	}
+	if pkg := db.Packages["linux5.10"]; pkg.PkgVer != "linux5.10-5.10.2_1" || pkg.AutomaticInstall {
+		t.Errorf("unexpected linux5.10 %+v", pkg)
+	}
+	if pkg := db.Packages["bar"]; pkg.PkgVer != "bar-1.0_1" || pkg.State != StateInstalled {
+		t.Errorf("unexpected bar %+v", pkg)
+	}
+	var raw map[string]map[string]any
+	buf, err := os.ReadFile(db.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plist.Unmarshal(buf, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["linux5.10"]["unknown-key"] != "preserved" {
+		t.Errorf("unknown key was not preserved: %v", raw["linux5.10"])
+	}
+	if _, ok := raw["_XBPS_ALTERNATIVES_"]; !ok {
+		t.Errorf("alternatives were not preserved")
+	}
+
+	empty := &DB{MetaDir: db.MetaDir, Packages: db.Packages}
+	if err := empty.Write(); !errors.Is(err, ErrNotRead) {
+		t.Fatalf("expected ErrNotRead, got %v", err)
+	}
+}
+
+// TestLockHelper holds the lock of PKGDB_LOCK_DIR until stdin is closed
+func TestLockHelper(t *testing.T) {
+	dir := os.Getenv("PKGDB_LOCK_DIR")
+	if dir == "" {
+		t.Skip("helper process")
+	}
+	lock, err := LockMetaDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+	os.Stdout.WriteString("locked\n")
+	bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+func TestLock(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("locking is not supported")
+	}
+	root := t.TempDir()
+	writeDB(t, root, modePkgDB, nil)
+	metadir := filepath.Join(root, MetaDir)
+
+	// record locks are per process, the lock is held by a helper process
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelper$")
+	cmd.Env = append(os.Environ(), "PKGDB_LOCK_DIR="+metadir)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "locked" {
+		t.Fatalf("helper did not acquire the lock: %q, %v", line, err)
+	}
+	if _, err := SetMode(root, ModeHold, "foo"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetMode(root, ModeHold, "foo"); err != nil {
+		t.Fatal(err)
+	}
+}
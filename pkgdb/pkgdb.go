@@ -0,0 +1,122 @@
+// Package pkgdb implements reading the xbps package database.
+//
+// The package database is stored in the metadata directory of the root
+// directory, by default <rootdir>/var/db/xbps. It consists of the
+// pkgdb-0.38.plist dictionary that maps package names to the installed
+// packages and a .<pkgname>-files.plist file list for each package.
+package pkgdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/repo"
+	"howett.net/plist"
+)
+
+const (
+	// MetaDir is the default metadata directory relative to the root directory
+	MetaDir = "var/db/xbps"
+	// Version is the version of the package database format
+	Version = "0.38"
+)
+
+// Package states
+const (
+	StateInstalled    = "installed"
+	StateUnpacked     = "unpacked"
+	StateHalfUnpacked = "half-unpacked"
+	StateHalfRemoved  = "half-removed"
+	StateNotInstalled = "not-installed"
+	StateBroken       = "broken"
+)
+
+// Package is an installed package
+type Package struct {
+	repo.Package
+	State            string `plist:"state,omitempty"`
+	AutomaticInstall bool   `plist:"automatic-install,omitempty"`
+	InstallDate      string `plist:"install-date,omitempty"`
+	Repository       string `plist:"repository,omitempty"`
+	Hold             bool   `plist:"hold,omitempty"`
+	RepoLock         bool   `plist:"repolock,omitempty"`
+	MetafileSHA256   string `plist:"metafile-sha256,omitempty"`
+}
+
+// DB is the package database
+type DB struct {
+	// RootDir is the root directory the packages are installed in
+	RootDir string
+	// MetaDir is the metadata directory
+	MetaDir string
+	// Packages maps package names to the installed packages
+	Packages map[string]Package
+
+	// raw is the undecoded package database, it is used for writing
+	// to preserve keys that are not part of Package.
+	raw map[string]any
+}
+
+// Open reads the package database of the root directory
+func Open(rootdir string) (*DB, error) {
+	return OpenMetaDir(rootdir, filepath.Join(rootdir, MetaDir))
+}
+
+// OpenMetaDir reads the package database from the metadata directory
+func OpenMetaDir(rootdir, metadir string) (*DB, error) {
+	db := &DB{RootDir: rootdir, MetaDir: metadir}
+	buf, err := os.ReadFile(db.Path())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plist.Unmarshal(buf, &db.Packages); err != nil {
+		return nil, fmt.Errorf("%s: %w", db.Path(), err)
+	}
+	if _, err := plist.Unmarshal(buf, &db.raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", db.Path(), err)
+	}
+	for name := range db.Packages {
+		// internal keys like _XBPS_ALTERNATIVES_ are not packages
+		if strings.HasPrefix(name, "_XBPS_") {
+			delete(db.Packages, name)
+		}
+	}
+	return db, nil
+}
+
+// Path returns the path of the package database plist
+func (db *DB) Path() string {
+	return filepath.Join(db.MetaDir, fmt.Sprintf("pkgdb-%s.plist", Version))
+}
+
+// Names returns the sorted names of the installed packages
+func (db *DB) Names() []string {
+	names := make([]string, 0, len(db.Packages))
+	for name := range db.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FilesPath returns the path of the file list of the package
+func (db *DB) FilesPath(name string) string {
+	return filepath.Join(db.MetaDir, fmt.Sprintf(".%s-files.plist", name))
+}
+
+// Files reads the file list of the installed package
+func (db *DB) Files(name string) (*binpkg.Files, error) {
+	buf, err := os.ReadFile(db.FilesPath(name))
+	if err != nil {
+		return nil, err
+	}
+	files := &binpkg.Files{}
+	if _, err := plist.Unmarshal(buf, files); err != nil {
+		return nil, fmt.Errorf("%s: %w", db.FilesPath(name), err)
+	}
+	return files, nil
+}
@@ -0,0 +1,87 @@
+package pkgdb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPkgDB = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>_XBPS_ALTERNATIVES_</key>
+	<dict>
+		<key>sh</key>
+		<array><string>dash</string></array>
+	</dict>
+	<key>foo</key>
+	<dict>
+		<key>pkgver</key>
+		<string>foo-1.0_1</string>
+		<key>state</key>
+		<string>installed</string>
+		<key>automatic-install</key>
+		<true/>
+		<key>repository</key>
+		<string>https://repo-default.voidlinux.org/current</string>
+	</dict>
+</dict>
+</plist>`
+
+const testFiles = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>files</key>
+	<array>
+		<dict>
+			<key>file</key>
+			<string>/usr/bin/foo</string>
+			<key>sha256</key>
+			<string>abcd</string>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+// writeDB writes a package database to the metadata directory of root
+func writeDB(t *testing.T, root, pkgdb string, files map[string]string) {
+	t.Helper()
+	metadir := filepath.Join(root, MetaDir)
+	if err := os.MkdirAll(metadir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(metadir, "pkgdb-"+Version+".plist"), []byte(pkgdb), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(metadir, "."+name+"-files.plist"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	root := t.TempDir()
+	writeDB(t, root, testPkgDB, map[string]string{"foo": testFiles})
+	db, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := db.Names(); !reflect.DeepEqual(names, []string{"foo"}) {
+		t.Fatalf("unexpected packages %v", names)
+	}
+	pkg := db.Packages["foo"]
+	if pkg.PkgVer != "foo-1.0_1" || pkg.State != StateInstalled || !pkg.AutomaticInstall {
+		t.Fatalf("unexpected package %+v", pkg)
+	}
+	files, err := db.Files("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files.Files) != 1 || files.Files[0].File != "/usr/bin/foo" || files.Files[0].SHA256 != "abcd" {
+		t.Fatalf("unexpected files %+v", files)
+	}
+}
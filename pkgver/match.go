@@ -0,0 +1,74 @@
+package pkgver
+
+import (
+	"path"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/version"
+)
+
+// Match reports whether pkgver matches the package pattern,
+// like xbps_pkgpattern_match.
+//
+// Patterns are either globs like foo-[0-9]*, version patterns like
+// foo>=1.0_1 or foo>=1.0_1<2.0_1, exact pkgvers or a package name
+// that matches all versions.
+func Match(pkgver, pattern string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := path.Match(pattern, pkgver)
+		return ok
+	}
+	pv := duckPkgver(pkgver)
+	pat, err := Parse(pattern)
+	if err != nil || pat.Name != pv.Name {
+		return false
+	}
+	switch {
+	case pat.Pattern != "":
+		return pv.Version != "" && matchVersion(pv.Version, pat.Pattern)
+	case pat.Version != "":
+		return pat.Version == pv.Version
+	default:
+		return true
+	}
+}
+
+// matchVersion checks the version against all relations of the pattern
+func matchVersion(v, pattern string) bool {
+	for pattern != "" {
+		op := pattern[:1]
+		if len(pattern) > 1 && pattern[1] == '=' {
+			op = pattern[:2]
+		}
+		pattern = pattern[len(op):]
+		end := strings.IndexAny(pattern, "<>=!")
+		if end == -1 {
+			end = len(pattern)
+		}
+		want := pattern[:end]
+		pattern = pattern[end:]
+		if want == "" {
+			return false
+		}
+		cmp := version.Cmp(v, want)
+		var ok bool
+		switch op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
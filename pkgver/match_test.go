@@ -0,0 +1,37 @@
+package pkgver
+
+import (
+	"testing"
+)
+
+var matchTests = []struct {
+	pkgver  string
+	pattern string
+	match   bool
+}{
+	{"foo-1.0_1", "foo", true},
+	{"foo-1.0_1", "bar", false},
+	{"foo-1.0_1", "foo-1.0_1", true},
+	{"foo-1.0_1", "foo-1.0_2", false},
+	{"foo-1.0_1", "foo>=1.0_1", true},
+	{"foo-1.0_1", "foo>1.0_1", false},
+	{"foo-1.0_1", "foo<1.1", true},
+	{"foo-1.0_1", "foo<=0.9_1", false},
+	{"foo-1.0_1", "foo==1.0_1", true},
+	{"foo-1.0_1", "foo!=1.0_1", false},
+	{"foo-1.5_1", "foo>=1.0_1<2.0_1", true},
+	{"foo-2.0_1", "foo>=1.0_1<2.0_1", false},
+	{"foo-32bit-1.0_1", "foo>=1.0_1", false},
+	{"foo-32bit-1.0_1", "foo-32bit>=1.0_1", true},
+	{"foo-1.0_1", "foo-[0-9]*", true},
+	{"bar-1.0_1", "foo-[0-9]*", false},
+	{"foo-1.0_1", "foo>", false},
+}
+
+func TestMatch(t *testing.T) {
+	for _, tt := range matchTests {
+		if got := Match(tt.pkgver, tt.pattern); got != tt.match {
+			t.Errorf("Match(%q, %q) = %v, expected %v", tt.pkgver, tt.pattern, got, tt.match)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"sort"
+	"strings"
+)
+
+// OptionState is the state of a build option
+type OptionState string
+
+const (
+	// OptionUnset is the state of options a package does not have
+	OptionUnset OptionState = ""
+	// OptionEnabled is the state of enabled options
+	OptionEnabled OptionState = "enabled"
+	// OptionDisabled is the state of disabled options
+	OptionDisabled OptionState = "disabled"
+)
+
+// BuildOptions are the build options of a package, mapping option
+// names to whether they are enabled.
+type BuildOptions map[string]bool
+
+// ParseBuildOptions parses a build-options string like "gtk3 -gtk2 ~x11",
+// options prefixed with ~ or - are disabled.
+func ParseBuildOptions(s string) BuildOptions {
+	opts := make(BuildOptions)
+	for _, f := range strings.Fields(s) {
+		switch {
+		case strings.HasPrefix(f, "~"), strings.HasPrefix(f, "-"):
+			if name := f[1:]; name != "" {
+				opts[name] = false
+			}
+		default:
+			opts[f] = true
+		}
+	}
+	return opts
+}
+
+// ParseBuildOptions parses the build options of the package
+func (p *Package) ParseBuildOptions() BuildOptions {
+	return ParseBuildOptions(p.BuildOptions)
+}
+
+// State returns the state of the option
+func (o BuildOptions) State(name string) OptionState {
+	enabled, ok := o[name]
+	switch {
+	case !ok:
+		return OptionUnset
+	case enabled:
+		return OptionEnabled
+	default:
+		return OptionDisabled
+	}
+}
+
+// Enabled returns the sorted enabled options
+func (o BuildOptions) Enabled() []string {
+	return o.list(true)
+}
+
+// Disabled returns the sorted disabled options
+func (o BuildOptions) Disabled() []string {
+	return o.list(false)
+}
+
+func (o BuildOptions) list(enabled bool) []string {
+	var res []string
+	for name, v := range o {
+		if v == enabled {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// String returns the options sorted by name, disabled options prefixed with ~
+func (o BuildOptions) String() string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if !o[name] {
+			names[i] = "~" + name
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// OptionChange is a build option that differs between two builds
+type OptionChange struct {
+	Name string      `json:"name"`
+	Old  OptionState `json:"old"`
+	New  OptionState `json:"new"`
+}
+
+// CompareBuildOptions returns the options that differ between the old
+// and new build sorted by name.
+func CompareBuildOptions(old, new BuildOptions) []OptionChange {
+	names := make(map[string]bool)
+	for name := range old {
+		names[name] = true
+	}
+	for name := range new {
+		names[name] = true
+	}
+	var res []OptionChange
+	for name := range names {
+		if o, n := old.State(name), new.State(name); o != n {
+			res = append(res, OptionChange{Name: name, Old: o, New: n})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// WithBuildOption returns the packages of the index where the option has
+// the state sorted by name, OptionUnset returns packages without the option.
+func (repo *Repository) WithBuildOption(name string, state OptionState) []Package {
+	var res []Package
+	for _, pkg := range repo.Index {
+		if pkg.ParseBuildOptions().State(name) == state {
+			res = append(res, pkg)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].PkgVer < res[j].PkgVer })
+	return res
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBuildOptions(t *testing.T) {
+	opts := ParseBuildOptions(" gtk3 -gtk2  ~x11 ~ ")
+	expect := BuildOptions{"gtk3": true, "gtk2": false, "x11": false}
+	if !reflect.DeepEqual(opts, expect) {
+		t.Fatalf("expected %v, got %v", expect, opts)
+	}
+	if s := opts.String(); s != "~gtk2 gtk3 ~x11" {
+		t.Errorf("unexpected string %q", s)
+	}
+	if e := opts.Enabled(); !reflect.DeepEqual(e, []string{"gtk3"}) {
+		t.Errorf("unexpected enabled options %v", e)
+	}
+	if d := opts.Disabled(); !reflect.DeepEqual(d, []string{"gtk2", "x11"}) {
+		t.Errorf("unexpected disabled options %v", d)
+	}
+	for name, state := range map[string]OptionState{
+		"gtk3": OptionEnabled,
+		"x11":  OptionDisabled,
+		"qt5":  OptionUnset,
+	} {
+		if s := opts.State(name); s != state {
+			t.Errorf("%s: expected state %q, got %q", name, state, s)
+		}
+	}
+}
+
+func TestCompareBuildOptions(t *testing.T) {
+	old := ParseBuildOptions("gtk3 ~pie ssp")
+	new := ParseBuildOptions("gtk3 pie ~x11")
+	expect := []OptionChange{
+		{Name: "pie", Old: OptionDisabled, New: OptionEnabled},
+		{Name: "ssp", Old: OptionEnabled, New: OptionUnset},
+		{Name: "x11", Old: OptionUnset, New: OptionDisabled},
+	}
+	if got := CompareBuildOptions(old, new); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected %v, got %v", expect, got)
+	}
+	if got := CompareBuildOptions(old, old); got != nil {
+		t.Fatalf("expected no changes, got %v", got)
+	}
+}
+
+func TestWithBuildOption(t *testing.T) {
+	r := &Repository{
+		Index: map[string]Package{
+			"foo": {PkgVer: "foo-1.0_1", BuildOptions: "pie ssp"},
+			"bar": {PkgVer: "bar-1.0_1", BuildOptions: "~pie"},
+			"baz": {PkgVer: "baz-1.0_1"},
+			"qux": {PkgVer: "qux-1.0_1", BuildOptions: "~ssp ~pie"},
+		},
+	}
+	pkgvers := func(pkgs []Package) []string {
+		var res []string
+		for _, pkg := range pkgs {
+			res = append(res, pkg.PkgVer)
+		}
+		return res
+	}
+	if got := pkgvers(r.WithBuildOption("pie", OptionDisabled)); !reflect.DeepEqual(got, []string{"bar-1.0_1", "qux-1.0_1"}) {
+		t.Errorf("unexpected packages without pie %v", got)
+	}
+	if got := pkgvers(r.WithBuildOption("pie", OptionEnabled)); !reflect.DeepEqual(got, []string{"foo-1.0_1"}) {
+		t.Errorf("unexpected packages with pie %v", got)
+	}
+	if got := pkgvers(r.WithBuildOption("ssp", OptionUnset)); !reflect.DeepEqual(got, []string{"bar-1.0_1", "baz-1.0_1"}) {
+		t.Errorf("unexpected packages without ssp option %v", got)
+	}
+}
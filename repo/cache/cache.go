@@ -0,0 +1,404 @@
+// Package cache implements a binary package cache like the xbps cachedir.
+//
+// Packages are stored as <pkgver>.<arch>.xbps next to their .sig2 signature
+// file, or .minisig for repositories signed with Ed25519 keys. Downloads
+// are written to a .part file first, they are resumed if the download is
+// interrupted and only moved into place once the packages sha256 hash and
+// signature are verified.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto"
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+var (
+	// ErrNotFound is returned if the package is not in the repository index
+	ErrNotFound = errors.New("package not found in repository")
+	// ErrHashMismatch is returned if a package does not match the indexed sha256 hash
+	ErrHashMismatch = errors.New("package sha256 hash mismatch")
+	// ErrUnsigned is returned when fetching from an unsigned remote repository
+	ErrUnsigned = errors.New("remote repository is not signed")
+)
+
+// PartialMaxAge is the age after which Clean removes partial downloads,
+// younger files may belong to downloads of other processes.
+const PartialMaxAge = 24 * time.Hour
+
+// Cache is a binary package cache directory.
+type Cache struct {
+	// Dir is the cache directory
+	Dir string
+	// Keys is the store of trusted keys, if Keys is nil the public key
+	// in the repository data is trusted.
+	Keys *repo.KeyStore
+	// Jobs is the number of concurrent downloads used by FetchAll,
+	// defaults to 4.
+	Jobs int
+	// RateLimit limits the bandwidth used by all downloads in bytes per
+	// second, zero means unlimited.
+	RateLimit int64
+	// Progress is called while packages are downloaded,
+	// calls are serialized.
+	Progress func(Progress)
+
+	mu         sync.Mutex
+	inflight   map[string]*call
+	limit      *limiter
+	progressMu sync.Mutex
+}
+
+// call is an in-flight or completed fetch
+type call struct {
+	done chan struct{}
+	err  error
+	// canceled is true if the context of the fetch was done
+	canceled bool
+}
+
+// New returns a cache for the directory
+func New(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// Path returns the path of the package in the cache
+func (c *Cache) Path(pkg *repo.Package) string {
+	return filepath.Join(c.Dir, pkg.Filename())
+}
+
+// lookup returns the package from the repository index
+func lookup(r *repo.Repository, name string) (*repo.Package, error) {
+	pkg, ok := r.Index[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return &pkg, nil
+}
+
+// Fetch downloads the package name from the repository into the cache and
+// returns its path.
+//
+// Packages that are already cached and verified are not downloaded again,
+// concurrent fetches of the same package are deduplicated.
+func (c *Cache) Fetch(ctx context.Context, r *repo.Repository, name string) (string, error) {
+	path, _, err := c.FetchCached(ctx, r, name)
+	return path, err
+}
+
+// FetchCached is like Fetch and additionally reports if the package was
+// already cached.
+func (c *Cache) FetchCached(ctx context.Context, r *repo.Repository, name string) (string, bool, error) {
+	pkg, err := lookup(r, name)
+	if err != nil {
+		return "", false, err
+	}
+	return c.fetchPackage(ctx, r, pkg, nil)
+}
+
+// fetchPackage deduplicates fetches of the same package.
+//
+// Callers that join a fetch in flight count the package towards their
+// progress once it is done and fetch it again if the fetch was canceled.
+func (c *Cache) fetchPackage(ctx context.Context, r *repo.Repository, pkg *repo.Package, agg *aggregate) (string, bool, error) {
+	path := c.Path(pkg)
+	p := c.newProgress(pkg, agg)
+	for {
+		c.mu.Lock()
+		if c.inflight == nil {
+			c.inflight = make(map[string]*call)
+		}
+		cl, ok := c.inflight[path]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-cl.done:
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		}
+		if cl.canceled && ctx.Err() == nil {
+			continue
+		}
+		if cl.err == nil {
+			p.set(pkg.FilenameSize)
+		}
+		return path, false, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.inflight[path] = cl
+	c.mu.Unlock()
+
+	var cached bool
+	cached, cl.err = c.fetch(ctx, r, pkg, path, p)
+	cl.canceled = ctx.Err() != nil
+
+	c.mu.Lock()
+	delete(c.inflight, path)
+	c.mu.Unlock()
+	close(cl.done)
+	return path, cached, cl.err
+}
+
+// fetch verifies or downloads the package, it returns true if the cached
+// package was valid.
+func (c *Cache) fetch(ctx context.Context, r *repo.Repository, pkg *repo.Package, path string, p *progress) (bool, error) {
+	key, err := c.publicKey(r)
+	if err != nil {
+		return false, err
+	}
+	if err := verify(path, pkg, key); err == nil {
+		p.set(pkg.FilenameSize)
+		return true, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		// remove packages that fail verification and download them again
+		removeAll(path, path+".sig2", path+minisign.Ext)
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return false, err
+	}
+	part := path + ".part"
+	hash, err := c.download(ctx, r, pkg, part, p)
+	if err != nil {
+		return false, err
+	}
+	if key != nil {
+		sig, err := fetchSignature(ctx, r, pkg, key.SignatureExt())
+		if err != nil {
+			return false, err
+		}
+		if err := verifySignature(part, hash, key, sig); err != nil {
+			os.Remove(part)
+			return false, fmt.Errorf("%s: signature verification failed: %w", pkg.PkgVer, err)
+		}
+		if err := os.WriteFile(path+key.SignatureExt(), sig, 0o644); err != nil {
+			return false, err
+		}
+	}
+	return false, os.Rename(part, path)
+}
+
+// publicKey returns the trusted public key of the repository or nil
+// if the repository is not signed.
+func (c *Cache) publicKey(r *repo.Repository) (*repo.PublicKey, error) {
+	if r.Meta == nil {
+		if r.URI != nil && r.URI.IsRemote() {
+			return nil, fmt.Errorf("%s: %w", r.URI, ErrUnsigned)
+		}
+		return nil, nil
+	}
+	key, err := r.Meta.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid public key: %w", r.URI, err)
+	}
+	if c.Keys != nil {
+		if err := c.Keys.Trusted(key); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// download downloads the package to path resuming existing partial downloads
+// and returns the packages sha256 hash.
+func (c *Cache) download(ctx context.Context, r *repo.Repository, pkg *repo.Package, path string, p *progress) ([]byte, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.FilenameSize > 0 && offset >= pkg.FilenameSize {
+		// already complete or bogus, start over if it does not verify
+		if hash, err := verifyFile(f, pkg); err == nil {
+			p.set(pkg.FilenameSize)
+			return hash, nil
+		}
+		offset = 0
+	}
+	rd, err := r.Fetch(ctx, pkg.Filename(), offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pkg.PkgVer, err)
+	}
+	defer rd.Close()
+	if err := f.Truncate(rd.Offset); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(rd.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	p.set(rd.Offset)
+	if _, err := io.Copy(f, &reader{ctx: ctx, r: rd, limit: c.limiter(), progress: p}); err != nil {
+		return nil, fmt.Errorf("%s: %w", pkg.PkgVer, err)
+	}
+	hash, err := verifyFile(f, pkg)
+	if err != nil {
+		// the partial file is useless
+		os.Remove(path)
+		return nil, err
+	}
+	return hash, nil
+}
+
+// fetchSignature downloads the packages signature file with the extension
+func fetchSignature(ctx context.Context, r *repo.Repository, pkg *repo.Package, ext string) ([]byte, error) {
+	rd, err := r.Fetch(ctx, pkg.Filename()+ext, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: signature: %w", pkg.PkgVer, err)
+	}
+	defer rd.Close()
+	// RSA and minisign signatures are at most a few kilobytes
+	return io.ReadAll(io.LimitReader(&reader{ctx: ctx, r: rd}, 64<<10))
+}
+
+// verifyFile checks the size and sha256 hash of f and returns the hash
+func verifyFile(f *os.File, pkg *repo.Package) ([]byte, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	hash := h.Sum(nil)
+	if pkg.FilenameSize > 0 && n != pkg.FilenameSize {
+		return nil, fmt.Errorf("%s: size %d does not match %d: %w", pkg.PkgVer, n, pkg.FilenameSize, ErrHashMismatch)
+	}
+	if !strings.EqualFold(hex.EncodeToString(hash), pkg.FilenameSHA256) {
+		return nil, fmt.Errorf("%s: %w", pkg.PkgVer, ErrHashMismatch)
+	}
+	return hash, nil
+}
+
+// Verify checks the cached package against the repository index and
+// verifies its signature if the repository is signed.
+func (c *Cache) Verify(r *repo.Repository, pkg *repo.Package) error {
+	key, err := c.publicKey(r)
+	if err != nil {
+		return err
+	}
+	return verify(c.Path(pkg), pkg, key)
+}
+
+// verify checks the package file at path and its signature if key is not nil
+func verify(path string, pkg *repo.Package, key *repo.PublicKey) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash, err := verifyFile(f, pkg)
+	if err != nil || key == nil {
+		return err
+	}
+	sig, err := os.ReadFile(path + key.SignatureExt())
+	if err != nil {
+		return err
+	}
+	if err := verifySignature(path, hash, key, sig); err != nil {
+		return fmt.Errorf("%s: signature verification failed: %w", pkg.PkgVer, err)
+	}
+	return nil
+}
+
+// verifySignature verifies the signature of the file at path with the sha256 hash
+func verifySignature(path string, hash []byte, key *repo.PublicKey, sig []byte) error {
+	if key.Minisign == nil {
+		return crypto.VerifySig2(key.Key, hash, sig)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return minisign.Verify(key.Minisign, f, sig)
+}
+
+// Clean removes obsolete files from the cache and returns their paths.
+//
+// Packages are obsolete if they are not in the index or stage of any of the
+// repositories, like xbps-remove -O does.
+// Stray signature files and partial downloads that are not in progress and
+// older than PartialMaxAge are removed too.
+func (c *Cache) Clean(repos ...*repo.Repository) ([]string, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	indexed := make(map[string]bool)
+	for _, r := range repos {
+		for _, idx := range []map[string]repo.Package{r.Index, r.Stage} {
+			for _, pkg := range idx {
+				indexed[pkg.Filename()] = true
+			}
+		}
+	}
+	var removed []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		var obsolete bool
+		switch {
+		case strings.HasSuffix(name, ".part"):
+			obsolete = !c.downloading(filepath.Join(c.Dir, strings.TrimSuffix(name, ".part"))) && stale(e)
+		case strings.HasSuffix(name, ".xbps"):
+			obsolete = !indexed[name]
+		case strings.HasSuffix(name, ".xbps.sig2"), strings.HasSuffix(name, ".xbps.sig"),
+			strings.HasSuffix(name, ".xbps"+minisign.Ext):
+			pkgfile := name[:strings.LastIndex(name, ".xbps")+len(".xbps")]
+			_, err := os.Stat(filepath.Join(c.Dir, pkgfile))
+			obsolete = errors.Is(err, fs.ErrNotExist) || !indexed[pkgfile]
+		default:
+			continue
+		}
+		if !obsolete {
+			continue
+		}
+		path := filepath.Join(c.Dir, name)
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// downloading returns true if the package at path is being fetched
+func (c *Cache) downloading(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.inflight[path]
+	return ok
+}
+
+// stale returns true if the file was not modified for PartialMaxAge
+func stale(e fs.DirEntry) bool {
+	info, err := e.Info()
+	return err == nil && time.Since(info.ModTime()) > PartialMaxAge
+}
+
+func removeAll(paths ...string) {
+	for _, p := range paths {
+		os.Remove(p)
+	}
+}
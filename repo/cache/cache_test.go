@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/internal/repotest"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+type testRepo struct {
+	*repotest.Repo
+	requests atomic.Int64
+	ranges   atomic.Int64
+	// hook is called with each request before it is served
+	hook func(*http.Request)
+}
+
+// newTestRepo serves a signed repository with the noarch packages over http
+func newTestRepo(t *testing.T, pkgs map[string]string) *testRepo {
+	t.Helper()
+	tr := &testRepo{Repo: repotest.New(t, "x86_64")}
+	for pkgver, content := range pkgs {
+		tr.Add(t, pkgver, "noarch", content)
+	}
+	tr.Hook = func(r *http.Request) {
+		tr.requests.Add(1)
+		if r.Header.Get("Range") != "" {
+			tr.ranges.Add(1)
+		}
+		if tr.hook != nil {
+			tr.hook(r)
+		}
+	}
+	return tr
+}
+
+func TestFetch(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": "foo package"})
+	c := New(t.TempDir())
+	path, cached, err := c.FetchCached(context.Background(), tr.Repository, "foo")
+	if err != nil || cached {
+		t.Fatalf("expected download, got %v, %v", cached, err)
+	}
+	if buf, err := os.ReadFile(path); err != nil || string(buf) != "foo package" {
+		t.Fatalf("unexpected cached package: %q, %v", buf, err)
+	}
+	if _, err := os.Stat(path + ".sig2"); err != nil {
+		t.Fatal(err)
+	}
+	n := tr.requests.Load()
+	if _, cached, err := c.FetchCached(context.Background(), tr.Repository, "foo"); err != nil || !cached {
+		t.Fatalf("expected cached package, got %v, %v", cached, err)
+	}
+	if tr.requests.Load() != n {
+		t.Fatal("cached package was downloaded again")
+	}
+	if _, err := c.Fetch(context.Background(), tr.Repository, "bar"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFetchMinisign(t *testing.T) {
+	tr := newTestRepo(t, nil)
+	priv, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := priv.Public().MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Meta = &repo.Meta{Key: key, Size: 256, SignedBy: "Test <test@example.org>"}
+	pkg := tr.Add(t, "foo-1.0_1", "noarch", "foo package")
+	sig, err := minisign.Sign(priv, strings.NewReader("foo package"), "file:"+pkg.Filename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := sig.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigfile := filepath.Join(tr.Dir, pkg.Filename()+minisign.Ext)
+	if err := os.WriteFile(sigfile, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(t.TempDir())
+	path, err := c.Fetch(context.Background(), tr.Repository, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + minisign.Ext); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Verify(tr.Repository, &pkg); err != nil {
+		t.Fatal(err)
+	}
+
+	// signature of another file
+	other, err := minisign.Sign(priv, strings.NewReader("bar package"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf, err = other.MarshalText(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+minisign.Ext, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Verify(tr.Repository, &pkg); !errors.Is(err, minisign.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if err := os.WriteFile(sigfile, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Fetch(context.Background(), tr.Repository, "foo"); !errors.Is(err, minisign.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestFetchResume(t *testing.T) {
+	content := strings.Repeat("foo package ", 1000)
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": content})
+	c := New(t.TempDir())
+	pkg := tr.Index["foo"]
+	if err := os.WriteFile(c.Path(&pkg)+".part", []byte(content[:100]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := c.Fetch(context.Background(), tr.Repository, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := os.ReadFile(path); err != nil || string(buf) != content {
+		t.Fatalf("resumed package does not match: %v", err)
+	}
+	if tr.ranges.Load() != 1 {
+		t.Fatalf("expected one range request, got %d", tr.ranges.Load())
+	}
+}
+
+func TestFetchHashMismatch(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": "foo package"})
+	pkg := tr.Index["foo"]
+	pkg.FilenameSHA256 = strings.Repeat("0", 64)
+	tr.Index["foo"] = pkg
+	c := New(t.TempDir())
+	if _, err := c.Fetch(context.Background(), tr.Repository, "foo"); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("expected ErrHashMismatch, got %v", err)
+	}
+	if _, err := os.Stat(c.Path(&pkg)); err == nil {
+		t.Fatal("package with mismatching hash was cached")
+	}
+}
+
+func TestFetchBadSignature(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": "foo package"})
+	pkg := tr.Index["foo"]
+	sig := filepath.Join(tr.Dir, pkg.Filename()+".sig2")
+	if err := os.WriteFile(sig, []byte("bogus"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(t.TempDir())
+	if _, err := c.Fetch(context.Background(), tr.Repository, "foo"); err == nil {
+		t.Fatal("expected signature verification to fail")
+	}
+	if _, err := os.Stat(c.Path(&pkg)); err == nil {
+		t.Fatal("package with bad signature was cached")
+	}
+}
+
+func TestFetchUntrusted(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": "foo package"})
+	c := New(t.TempDir())
+	c.Keys = &repo.KeyStore{Dir: t.TempDir()}
+	if _, err := c.Fetch(context.Background(), tr.Repository, "foo"); !errors.Is(err, repo.ErrUntrusted) {
+		t.Fatalf("expected ErrUntrusted, got %v", err)
+	}
+	key, err := tr.Meta.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Keys.Add(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Fetch(context.Background(), tr.Repository, "foo"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchDedupe(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": "foo package"})
+	c := New(t.TempDir())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Fetch(context.Background(), tr.Repository, "foo"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// one request for the package and one for the signature
+	if n := tr.requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestClean(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": "foo package", "bar-1.0_1": "bar package"})
+	c := New(t.TempDir())
+	for _, name := range []string{"foo", "bar"} {
+		if _, err := c.Fetch(context.Background(), tr.Repository, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * PartialMaxAge)
+	for _, name := range []string{"baz-1.0_1.noarch.xbps.part", "qux-1.0_1.noarch.xbps.part", "new-1.0_1.noarch.xbps.part"} {
+		path := filepath.Join(c.Dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "new-1.0_1.noarch.xbps.part" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// downloads in progress are kept
+	c.inflight = map[string]*call{filepath.Join(c.Dir, "qux-1.0_1.noarch.xbps"): {done: make(chan struct{})}}
+	tr.Add(t, "foo-1.1_1", "noarch", "new foo package")
+	removed, err := c.Clean(tr.Repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"baz-1.0_1.noarch.xbps.part",
+		"foo-1.0_1.noarch.xbps",
+		"foo-1.0_1.noarch.xbps.sig2",
+	}
+	if len(removed) != len(want) {
+		t.Fatalf("expected %v to be removed, got %v", want, removed)
+	}
+	for i := range want {
+		if filepath.Base(removed[i]) != want[i] {
+			t.Fatalf("expected %v to be removed, got %v", want, removed)
+		}
+	}
+}
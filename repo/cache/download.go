@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+// defaultJobs is the default number of concurrent downloads of FetchAll
+const defaultJobs = 4
+
+// Progress is the progress of a package download and of all
+// downloads started by the same FetchAll call.
+type Progress struct {
+	// PkgVer is the package that is being downloaded
+	PkgVer string
+	// Done is the number of bytes of the package that are downloaded
+	Done int64
+	// Total is the size of the package from the repository index
+	Total int64
+	// AllDone is the number of bytes downloaded of all packages
+	AllDone int64
+	// AllTotal is the size of all packages
+	AllTotal int64
+}
+
+// aggregate tracks the progress of all downloads of a FetchAll call
+type aggregate struct {
+	done  atomic.Int64
+	total int64
+}
+
+// progress tracks the progress of a single download
+type progress struct {
+	c    *Cache
+	agg  *aggregate
+	pkg  *repo.Package
+	done int64
+}
+
+func (c *Cache) newProgress(pkg *repo.Package, agg *aggregate) *progress {
+	if agg == nil {
+		agg = &aggregate{total: pkg.FilenameSize}
+	}
+	return &progress{c: c, agg: agg, pkg: pkg}
+}
+
+// set sets the number of downloaded bytes, used when resuming or restarting downloads
+func (p *progress) set(n int64) {
+	p.add(n - p.done)
+}
+
+func (p *progress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.done += n
+	all := p.agg.done.Add(n)
+	if p.c.Progress == nil {
+		return
+	}
+	p.c.progressMu.Lock()
+	defer p.c.progressMu.Unlock()
+	p.c.Progress(Progress{
+		PkgVer:   p.pkg.PkgVer,
+		Done:     p.done,
+		Total:    p.pkg.FilenameSize,
+		AllDone:  all,
+		AllTotal: p.agg.total,
+	})
+}
+
+// FetchAll downloads the packages names from the repository into the cache
+// using up to Jobs concurrent downloads and returns their paths.
+//
+// If a download fails all other downloads are canceled and the first error
+// is returned.
+func (c *Cache) FetchAll(ctx context.Context, r *repo.Repository, names ...string) ([]string, error) {
+	agg := &aggregate{}
+	pkgs := make([]*repo.Package, len(names))
+	for i, name := range names {
+		pkg, err := lookup(r, name)
+		if err != nil {
+			return nil, err
+		}
+		pkgs[i] = pkg
+		agg.total += pkg.FilenameSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := c.Jobs
+	if jobs <= 0 {
+		jobs = defaultJobs
+	}
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		sem      = make(chan struct{}, jobs)
+		paths    = make([]string, len(pkgs))
+	)
+	for i, pkg := range pkgs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			path, _, err := c.fetchPackage(ctx, r, pkg, agg)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			paths[i] = path
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// limiter limits the bandwidth shared by all downloads of a cache
+type limiter struct {
+	mu   sync.Mutex
+	rate int64
+	next time.Time
+}
+
+// chunk returns how many bytes should be read at once
+func (l *limiter) chunk(n int) int {
+	max := int(l.rate / 8)
+	if max < 1 {
+		max = 1
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// wait blocks until n more bytes may be read
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limiter returns the rate limiter or nil if downloads are not limited
+func (c *Cache) limiter() *limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.RateLimit <= 0 {
+		return nil
+	}
+	if c.limit == nil || c.limit.rate != c.RateLimit {
+		c.limit = &limiter{rate: c.RateLimit}
+	}
+	return c.limit
+}
+
+// reader is the reader used for downloads, it stops once the context
+// is done and reports progress and limits the bandwidth
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limit    *limiter
+	progress *progress
+}
+
+func (rd *reader) Read(p []byte) (int, error) {
+	if err := rd.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if rd.limit != nil {
+		p = p[:rd.limit.chunk(len(p))]
+	}
+	n, err := rd.r.Read(p)
+	if n > 0 {
+		rd.progress.add(int64(n))
+		if rd.limit != nil {
+			if err := rd.limit.wait(rd.ctx, n); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/repo/uri"
+)
+
+func TestFetchAll(t *testing.T) {
+	pkgs := make(map[string]string)
+	var names []string
+	var total int64
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("pkg%d", i)
+		content := strings.Repeat(name, 1000*(i+1))
+		pkgs[name+"-1.0_1"] = content
+		names = append(names, name)
+		total += int64(len(content))
+	}
+	tr := newTestRepo(t, pkgs)
+	c := New(t.TempDir())
+	c.Jobs = 3
+	var last Progress
+	done := make(map[string]int64)
+	c.Progress = func(p Progress) {
+		if p.AllDone < last.AllDone {
+			t.Errorf("aggregate progress went backwards: %d < %d", p.AllDone, last.AllDone)
+		}
+		if p.Done > p.Total {
+			t.Errorf("%s: progress %d exceeds total %d", p.PkgVer, p.Done, p.Total)
+		}
+		done[p.PkgVer] = p.Done
+		last = p
+	}
+	paths, err := c.FetchAll(context.Background(), tr.Repository, names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != len(names) {
+		t.Fatalf("expected %d paths, got %d", len(names), len(paths))
+	}
+	for i, name := range names {
+		pkg := tr.Index[name]
+		if paths[i] != c.Path(&pkg) {
+			t.Errorf("expected path %q, got %q", c.Path(&pkg), paths[i])
+		}
+		if done[pkg.PkgVer] != pkg.FilenameSize {
+			t.Errorf("%s: progress ended at %d of %d", pkg.PkgVer, done[pkg.PkgVer], pkg.FilenameSize)
+		}
+	}
+	if last.AllDone != total || last.AllTotal != total {
+		t.Fatalf("expected aggregate progress %d/%d, got %d/%d", total, total, last.AllDone, last.AllTotal)
+	}
+}
+
+func TestFetchAllCancel(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+	u, err := uri.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &repo.Repository{URI: u, Meta: newTestRepo(t, nil).Meta, Index: map[string]repo.Package{}}
+	for _, name := range []string{"foo", "bar", "baz"} {
+		r.Index[name] = repo.Package{PkgVer: name + "-1.0_1", Architecture: "noarch", FilenameSize: 1000}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = New(t.TempDir()).FetchAll(ctx, r, "foo", "bar", "baz")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("cancellation took %s", d)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	content := strings.Repeat("x", 4000)
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": content})
+	c := New(t.TempDir())
+	c.RateLimit = 10000
+	start := time.Now()
+	if _, err := c.Fetch(context.Background(), tr.Repository, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 300*time.Millisecond {
+		t.Fatalf("downloading %d bytes at %d bytes/s took only %s", len(content), c.RateLimit, d)
+	}
+}
+
+func TestFetchAllOverlap(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{
+		"foo-1.0_1": strings.Repeat("foo", 1000),
+		"bar-1.0_1": strings.Repeat("bar", 2000),
+		"baz-1.0_1": strings.Repeat("baz", 4000),
+	})
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	tr.hook = func(r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/foo-1.0_1.noarch.xbps") {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+		}
+	}
+	c := New(t.TempDir())
+	var mu sync.Mutex
+	// the calls are told apart by their total size
+	done := make(map[int64]int64)
+	c.Progress = func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.AllDone > p.AllTotal {
+			t.Errorf("aggregate progress %d exceeds total %d", p.AllDone, p.AllTotal)
+		}
+		done[p.AllTotal] = p.AllDone
+	}
+	size := func(names ...string) int64 {
+		var n int64
+		for _, name := range names {
+			n += tr.Index[name].FilenameSize
+		}
+		return n
+	}
+
+	var wg sync.WaitGroup
+	fetch := func(names ...string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.FetchAll(context.Background(), tr.Repository, names...); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	fetch("foo", "bar")
+	<-started
+	// joins the download of foo and fetches baz twice
+	fetch("foo", "baz", "baz")
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for _, names := range [][]string{{"foo", "bar"}, {"foo", "baz", "baz"}} {
+		if total := size(names...); done[total] != total {
+			t.Errorf("%v: progress ended at %d of %d", names, done[total], total)
+		}
+	}
+}
+
+func TestFetchJoinCanceled(t *testing.T) {
+	tr := newTestRepo(t, map[string]string{"foo-1.0_1": strings.Repeat("foo", 1000)})
+	started := make(chan struct{})
+	var once sync.Once
+	tr.hook = func(r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".xbps") {
+			// block the first download until it is canceled
+			first := false
+			once.Do(func() { first = true })
+			if first {
+				close(started)
+				<-r.Context().Done()
+			}
+		}
+	}
+	c := New(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	owner := make(chan error, 1)
+	go func() {
+		_, err := c.Fetch(ctx, tr.Repository, "foo")
+		owner <- err
+	}()
+	<-started
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := c.Fetch(context.Background(), tr.Repository, "foo")
+		waiter <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-owner; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	select {
+	case err := <-waiter:
+		if err != nil {
+			t.Fatalf("waiter failed with the owners error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter did not finish")
+	}
+}
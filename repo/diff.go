@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/version"
+)
+
+// FieldChange describes a changed package metadata field.
+type FieldChange struct {
+	// Field is the plist key of the changed field
+	Field string `json:"field"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+}
+
+// PackageChange describes the change of a single package between two repositories.
+type PackageChange struct {
+	Name string `json:"name"`
+	// Old is the pkgver in the old repository, empty for added packages
+	Old string `json:"old,omitempty"`
+	// New is the pkgver in the new repository, empty for removed packages
+	New string `json:"new,omitempty"`
+	// Fields lists the changed metadata fields, pkgver changes are not included
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// Move describes a package that moved between the index and the stage.
+type Move struct {
+	Name string `json:"name"`
+	// From is the entry the package was in, either IndexEntry or StageEntry
+	From string `json:"from"`
+	// To is the entry the package is in now, either IndexEntry or StageEntry
+	To     string `json:"to"`
+	PkgVer string `json:"pkgver"`
+}
+
+// Diff is the difference between two repositories.
+//
+// All lists are sorted by package name, which makes the JSON
+// encoding of a Diff stable.
+type Diff struct {
+	Added      []PackageChange `json:"added,omitempty"`
+	Removed    []PackageChange `json:"removed,omitempty"`
+	Upgraded   []PackageChange `json:"upgraded,omitempty"`
+	Downgraded []PackageChange `json:"downgraded,omitempty"`
+	// Modified lists packages with the same version but changed metadata
+	Modified []PackageChange `json:"modified,omitempty"`
+	// Unordered lists packages with a changed pkgver that cannot be
+	// ordered because one of the pkgvers is malformed
+	Unordered []PackageChange `json:"unordered,omitempty"`
+	// Staged is the difference between the repositories stages
+	Staged *Diff `json:"staged,omitempty"`
+	// Moved lists packages that moved between index and stage
+	Moved []Move `json:"moved,omitempty"`
+}
+
+// Empty returns true if there are no differences.
+func (d *Diff) Empty() bool {
+	return d == nil || (len(d.Added) == 0 && len(d.Removed) == 0 &&
+		len(d.Upgraded) == 0 && len(d.Downgraded) == 0 &&
+		len(d.Modified) == 0 && len(d.Unordered) == 0 && d.Staged.Empty() && len(d.Moved) == 0)
+}
+
+// NewDiff compares the index and stage of the old and new repository.
+func NewDiff(old, new *Repository) *Diff {
+	d := diffIndex(old.Index, new.Index)
+	if staged := diffIndex(old.Stage, new.Stage); !staged.Empty() {
+		d.Staged = staged
+	}
+	d.Moved = append(d.Moved, moves(old.Index, old.Stage, new.Stage, IndexEntry, StageEntry)...)
+	d.Moved = append(d.Moved, moves(old.Stage, old.Index, new.Index, StageEntry, IndexEntry)...)
+	sort.Slice(d.Moved, func(i, j int) bool {
+		return d.Moved[i].Name < d.Moved[j].Name
+	})
+	return d
+}
+
+// moves returns packages from src that appear with the same pkgver in newDst
+// but were not already in oldDst.
+func moves(src, oldDst, newDst map[string]Package, from, to string) []Move {
+	var res []Move
+	for name, opkg := range src {
+		npkg, ok := newDst[name]
+		if !ok || npkg.PkgVer != opkg.PkgVer {
+			continue
+		}
+		if prev, ok := oldDst[name]; ok && prev.PkgVer == npkg.PkgVer {
+			continue
+		}
+		res = append(res, Move{Name: name, From: from, To: to, PkgVer: npkg.PkgVer})
+	}
+	return res
+}
+
+func diffIndex(old, new map[string]Package) *Diff {
+	d := &Diff{}
+	for name, opkg := range old {
+		npkg, ok := new[name]
+		if !ok {
+			d.Removed = append(d.Removed, PackageChange{Name: name, Old: opkg.PkgVer})
+			continue
+		}
+		c := PackageChange{
+			Name:   name,
+			Old:    opkg.PkgVer,
+			New:    npkg.PkgVer,
+			Fields: diffFields(&opkg, &npkg),
+		}
+		cmp, ok := cmpPkgVer(opkg.PkgVer, npkg.PkgVer)
+		switch {
+		case !ok:
+			if opkg.PkgVer != npkg.PkgVer || len(c.Fields) > 0 {
+				d.Unordered = append(d.Unordered, c)
+			}
+		case cmp == -1:
+			d.Upgraded = append(d.Upgraded, c)
+		case cmp == 1:
+			d.Downgraded = append(d.Downgraded, c)
+		default:
+			if len(c.Fields) > 0 {
+				d.Modified = append(d.Modified, c)
+			}
+		}
+	}
+	for name, npkg := range new {
+		if _, ok := old[name]; !ok {
+			d.Added = append(d.Added, PackageChange{Name: name, New: npkg.PkgVer})
+		}
+	}
+	for _, l := range [][]PackageChange{d.Added, d.Removed, d.Upgraded, d.Downgraded, d.Modified, d.Unordered} {
+		sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	}
+	return d
+}
+
+// cmpPkgVer compares the versions of two pkgver strings, ok is false
+// if either pkgver is malformed.
+func cmpPkgVer(a, b string) (cmp int, ok bool) {
+	pa, err := pkgver.Parse(a)
+	if err != nil || pa.Version == "" {
+		return 0, false
+	}
+	pb, err := pkgver.Parse(b)
+	if err != nil || pb.Version == "" {
+		return 0, false
+	}
+	return version.Cmp(pa.Version, pb.Version), true
+}
+
+// diffFields returns the changed fields of a and b, using the plist keys as field names.
+func diffFields(a, b *Package) []FieldChange {
+	var res []FieldChange
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("plist"), ",")
+		if key == "pkgver" {
+			continue
+		}
+		fa, fb := va.Field(i), vb.Field(i)
+		if fa.IsZero() && fb.IsZero() {
+			continue
+		}
+		if reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			continue
+		}
+		c := FieldChange{Field: key}
+		if !fa.IsZero() {
+			c.Old = fa.Interface()
+		}
+		if !fb.IsZero() {
+			c.New = fb.Interface()
+		}
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Field < res[j].Field })
+	return res
+}
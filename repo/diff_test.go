@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Repository{
+		Index: map[string]Package{
+			"foo":  {PkgVer: "foo-1.0_1", RunDepends: []string{"libbar>=1.0_1"}},
+			"bar":  {PkgVer: "bar-2.0_1"},
+			"baz":  {PkgVer: "baz-1.0_1", License: "MIT"},
+			"gone": {PkgVer: "gone-1.0_1"},
+			"same": {PkgVer: "same-1.0_1"},
+			"qux":  {PkgVer: "qux-1.0_1"},
+			"bad":  {PkgVer: "bad-1.0_1"},
+		},
+		Stage: map[string]Package{
+			"qux": {PkgVer: "qux-2.0_1"},
+		},
+	}
+	new := &Repository{
+		Index: map[string]Package{
+			"foo":  {PkgVer: "foo-1.1_1", RunDepends: []string{"libbar>=1.1_1"}},
+			"bar":  {PkgVer: "bar-2.0rc1_1"},
+			"baz":  {PkgVer: "baz-1.0_1", License: "BSD-2-Clause"},
+			"new":  {PkgVer: "new-1.0_1"},
+			"same": {PkgVer: "same-1.0_1"},
+			"qux":  {PkgVer: "qux-2.0_1"},
+			"bad":  {PkgVer: "bad"},
+		},
+	}
+	d := NewDiff(old, new)
+	if d.Empty() {
+		t.Fatal("expected differences")
+	}
+	if len(d.Added) != 1 || d.Added[0].Name != "new" {
+		t.Errorf("added: got %v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Name != "gone" {
+		t.Errorf("removed: got %v", d.Removed)
+	}
+	if len(d.Upgraded) != 2 || d.Upgraded[0].Name != "foo" || d.Upgraded[1].Name != "qux" {
+		t.Errorf("upgraded: got %v", d.Upgraded)
+	}
+	if f := d.Upgraded[0].Fields; len(f) != 1 || f[0].Field != "run_depends" {
+		t.Errorf("upgraded fields: got %v", f)
+	}
+	if len(d.Downgraded) != 1 || d.Downgraded[0].Name != "bar" {
+		t.Errorf("downgraded: got %v", d.Downgraded)
+	}
+	if len(d.Modified) != 1 || d.Modified[0].Name != "baz" || d.Modified[0].Fields[0].Field != "license" {
+		t.Errorf("modified: got %v", d.Modified)
+	}
+	// malformed pkgvers are not ordered
+	if len(d.Unordered) != 1 || d.Unordered[0].Name != "bad" {
+		t.Errorf("unordered: got %v", d.Unordered)
+	}
+	if d.Staged == nil || len(d.Staged.Removed) != 1 {
+		t.Errorf("staged: got %v", d.Staged)
+	}
+	if len(d.Moved) != 1 || d.Moved[0] != (Move{Name: "qux", From: StageEntry, To: IndexEntry, PkgVer: "qux-2.0_1"}) {
+		t.Errorf("moved: got %v", d.Moved)
+	}
+
+	a, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(NewDiff(old, new))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Fatalf("json encoding is not stable:\n%s\n%s", a, b)
+	}
+}
+
+func TestDiffEmpty(t *testing.T) {
+	r := &Repository{Index: map[string]Package{"foo": {PkgVer: "foo-1.0_1"}}}
+	if d := NewDiff(r, r); !d.Empty() {
+		t.Fatalf("expected no differences, got %v", d)
+	}
+}
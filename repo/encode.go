@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"archive/tar"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"howett.net/plist"
+)
+
+// writeCounter is a io.Writer wrapper that counts the number of bytes written
+type writeCounter struct {
+	io.Writer
+	n int64
+}
+
+// Write implementation that counts bytes written
+func (counter *writeCounter) Write(p []byte) (int, error) {
+	n, err := counter.Writer.Write(p)
+	counter.n += int64(n)
+	return n, err
+}
+
+// Encoder is a repository data encoder
+type Encoder struct {
+	writer  writeCounter
+	comp    *zstd.Encoder
+	archive *tar.Writer
+	// ModTime is the modification time of the written entries
+	ModTime time.Time
+}
+
+// Create a new repository data encoder
+func NewEncoder(w io.Writer) (*Encoder, error) {
+	var err error
+	enc := &Encoder{
+		writer:  writeCounter{w, 0},
+		ModTime: time.Now(),
+	}
+	enc.comp, err = zstd.NewWriter(&enc.writer)
+	if err != nil {
+		return nil, err
+	}
+	enc.archive = tar.NewWriter(enc.comp)
+	return enc, nil
+}
+
+// WritePlist encodes v as plist and writes it as repository entry name
+func (enc *Encoder) WritePlist(name string, v any) error {
+	buf, err := plist.MarshalIndent(v, plist.XMLFormat, "\t")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(buf)),
+		ModTime: enc.ModTime,
+		Format:  tar.FormatPAX,
+	}
+	if err := enc.archive.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = enc.archive.Write(buf)
+	return err
+}
+
+// Close flushes and closes the repository data, it does not close the underlying writer
+func (enc *Encoder) Close() error {
+	if err := enc.archive.Close(); err != nil {
+		return err
+	}
+	return enc.comp.Close()
+}
+
+// WriteTo writes the repository data to the writer
+func (repo *Repository) WriteTo(w io.Writer) (int64, error) {
+	enc, err := NewEncoder(w)
+	if err != nil {
+		return 0, err
+	}
+	index := repo.Index
+	if index == nil {
+		index = map[string]Package{}
+	}
+	if err := enc.WritePlist(IndexEntry, index); err != nil {
+		return enc.writer.n, err
+	}
+	if repo.Meta != nil {
+		if err := enc.WritePlist(MetaEntry, repo.Meta); err != nil {
+			return enc.writer.n, err
+		}
+	}
+	if len(repo.Stage) > 0 {
+		if err := enc.WritePlist(StageEntry, repo.Stage); err != nil {
+			return enc.writer.n, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return enc.writer.n, err
+	}
+	return enc.writer.n, nil
+}
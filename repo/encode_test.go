@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := &Repository{
+		Index: map[string]Package{
+			"foo": {
+				PkgVer:         "foo-1.0_1",
+				Architecture:   "x86_64",
+				RunDepends:     []string{"bar>=1.0_1"},
+				FilenameSize:   1234,
+				FilenameSHA256: "abcd",
+				Alternatives:   map[string][]string{"sh": {"/usr/bin/sh:/usr/bin/foo"}},
+			},
+		},
+		Stage: map[string]Package{
+			"bar": {PkgVer: "bar-2.0_1", Architecture: "noarch"},
+		},
+		Meta: &Meta{Key: []byte("key"), Size: 4096, SignedBy: "Test <test@example.org>"},
+	}
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+	res := &Repository{}
+	if _, err := res.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Index, res.Index) || !reflect.DeepEqual(r.Stage, res.Stage) || !reflect.DeepEqual(r.Meta, res.Meta) {
+		t.Fatalf("expected %+v, got %+v", r, res)
+	}
+}
@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+)
+
+// DefaultPollInterval is the default interval Watch checks the repository data
+const DefaultPollInterval = 10 * time.Second
+
+// Handle is a concurrency safe handle to a repository that reloads the
+// repository data when the file changes.
+//
+// Readers get immutable snapshots of the repository, new repository data
+// is swapped in atomically after it was fully decoded.
+type Handle struct {
+	// Interval is the poll interval of Watch, defaults to DefaultPollInterval
+	Interval time.Duration
+	// OnError is called with errors of reloads from Watch
+	OnError func(error)
+
+	path    string
+	current atomic.Pointer[Repository]
+
+	// reload serializes reloads and the calls of subscribers
+	reload sync.Mutex
+	info   os.FileInfo
+
+	mu   sync.Mutex
+	subs map[int]func(*Diff)
+	next int
+}
+
+// NewHandle returns a handle for the repository data of the repository
+// and loads it. The Arch, URI, Mirrors, CacheDir, IndexCache and RepodataKey
+// of r are used for the loaded repositories.
+func NewHandle(r *Repository) (*Handle, error) {
+	path, err := r.URI.Repodata(r.Arch, r.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+	h := &Handle{path: path, subs: make(map[int]func(*Diff))}
+	h.current.Store(r.config())
+	if _, err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// config returns a repository without data with the configuration of r
+func (r *Repository) config() *Repository {
+	return &Repository{
+		Arch:        r.Arch,
+		URI:         r.URI,
+		Mirrors:     r.Mirrors,
+		CacheDir:    r.CacheDir,
+		IndexCache:  r.IndexCache,
+		RepodataKey: r.RepodataKey,
+	}
+}
+
+// Path returns the path of the repository data
+func (h *Handle) Path() string {
+	return h.path
+}
+
+// Repository returns the current repository, it must not be modified
+func (h *Handle) Repository() *Repository {
+	return h.current.Load()
+}
+
+// Subscribe calls fn with the differences of each reload that changed the
+// repository, until the returned cancel function is called. Subscribers
+// are called in order of reloads, they may subscribe or cancel but must
+// not call Reload.
+func (h *Handle) Subscribe(fn func(*Diff)) (cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := h.next
+	h.next++
+	h.subs[id] = fn
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs, id)
+	}
+}
+
+// Reload reads the repository data if the file changed since the last
+// load and returns true if the repository was replaced. The current
+// repository is kept if the new repository data does not decode.
+func (h *Handle) Reload() (bool, error) {
+	h.reload.Lock()
+	defer h.reload.Unlock()
+	f, err := os.Open(h.path)
+	if err != nil {
+		return false, fmt.Errorf("repo could not be reloaded: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("repo could not be reloaded: %w", err)
+	}
+	if h.info != nil && os.SameFile(h.info, info) &&
+		h.info.ModTime().Equal(info.ModTime()) && h.info.Size() == info.Size() {
+		return false, nil
+	}
+	old := h.current.Load()
+	r := old.config()
+	sig := func() ([]byte, error) {
+		sig, err := os.ReadFile(h.path + minisign.Ext)
+		if err != nil {
+			return nil, fmt.Errorf("repodata signature: %w", err)
+		}
+		return sig, nil
+	}
+	if err := r.read(f, sig); err != nil {
+		return false, fmt.Errorf("repo could not be reloaded: %w", err)
+	}
+	h.current.Store(r)
+	first := h.info == nil
+	h.info = info
+	if first {
+		return true, nil
+	}
+	if d := NewDiff(old, r); !d.Empty() {
+		h.mu.Lock()
+		subs := make([]func(*Diff), 0, len(h.subs))
+		for _, fn := range h.subs {
+			subs = append(subs, fn)
+		}
+		h.mu.Unlock()
+		for _, fn := range subs {
+			fn(d)
+		}
+	}
+	return true, nil
+}
+
+// Watch reloads the repository data every Interval until the context is done
+func (h *Handle) Watch(ctx context.Context) error {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if _, err := h.Reload(); err != nil && h.OnError != nil {
+				h.OnError(err)
+			}
+		}
+	}
+}
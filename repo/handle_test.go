@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+)
+
+// replaceRepodata atomically replaces the repository data in dir
+func replaceRepodata(t *testing.T, dir string, index map[string]Package) {
+	t.Helper()
+	tmp := filepath.Join(dir, ".repodata")
+	writeRepodata(t, tmp, index)
+	if err := os.Rename(tmp, filepath.Join(dir, "x86_64-repodata")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	dir := t.TempDir()
+	replaceRepodata(t, dir, map[string]Package{"foo": {PkgVer: "foo-1.0_1"}})
+	r, err := New(dir, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewHandle(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg := h.Repository().Index["foo"]; pkg.PkgVer != "foo-1.0_1" {
+		t.Fatalf("unexpected package %+v", pkg)
+	}
+	diffs := make(chan *Diff, 1)
+	cancel := h.Subscribe(func(d *Diff) { diffs <- d })
+
+	if ok, err := h.Reload(); ok || err != nil {
+		t.Fatalf("expected no reload of unchanged repodata, got %v, %v", ok, err)
+	}
+
+	// broken repodata keeps the current repository
+	if err := os.WriteFile(filepath.Join(dir, "x86_64-repodata"), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Reload(); err == nil {
+		t.Fatal("expected error for broken repodata")
+	}
+	if h.Repository().Index["foo"].PkgVer != "foo-1.0_1" {
+		t.Fatal("repository was replaced by broken repodata")
+	}
+
+	old := h.Repository()
+	replaceRepodata(t, dir, map[string]Package{"foo": {PkgVer: "foo-1.1_1"}, "bar": {PkgVer: "bar-1.0_1"}})
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				_ = h.Repository().Index["foo"]
+			}
+		}()
+	}
+	h.Interval = 10 * time.Millisecond
+	go h.Watch(ctx)
+	select {
+	case d := <-diffs:
+		if len(d.Added) != 1 || d.Added[0].Name != "bar" || len(d.Upgraded) != 1 {
+			t.Errorf("unexpected diff %+v", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("repository was not reloaded")
+	}
+	stop()
+	wg.Wait()
+	if h.Repository().Index["foo"].PkgVer != "foo-1.1_1" || old.Index["foo"].PkgVer != "foo-1.0_1" {
+		t.Fatal("repository snapshot was modified")
+	}
+
+	cancel()
+	replaceRepodata(t, dir, map[string]Package{})
+	if ok, err := h.Reload(); !ok || err != nil {
+		t.Fatalf("expected reload, got %v, %v", ok, err)
+	}
+	select {
+	case d := <-diffs:
+		t.Fatalf("cancelled subscriber was called with %+v", d)
+	default:
+	}
+}
+
+func TestHandleSubscribeReentrant(t *testing.T) {
+	dir := t.TempDir()
+	replaceRepodata(t, dir, map[string]Package{"foo": {PkgVer: "foo-1.0_1"}})
+	r, err := New(dir, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewHandle(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a subscriber that cancels itself and subscribes another one
+	var cancel func()
+	called := 0
+	cancel = h.Subscribe(func(*Diff) {
+		cancel()
+		h.Subscribe(func(*Diff) { called++ })
+	})
+	replaceRepodata(t, dir, map[string]Package{"foo": {PkgVer: "foo-1.1_1"}})
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.Reload()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload deadlocked")
+	}
+	replaceRepodata(t, dir, map[string]Package{"foo": {PkgVer: "foo-1.2_1"}})
+	if _, err := h.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if called != 1 {
+		t.Fatalf("expected the new subscriber to be called once, got %d", called)
+	}
+}
+
+func TestHandleRepodataKey(t *testing.T) {
+	dir := t.TempDir()
+	priv, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sign signs the repository data in dir
+	sign := func() {
+		t.Helper()
+		f, err := os.Open(filepath.Join(dir, "x86_64-repodata"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		sig, err := minisign.Sign(priv, f, "file:x86_64-repodata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := sig.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "x86_64-repodata"+minisign.Ext), buf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replaceRepodata(t, dir, map[string]Package{"foo": {PkgVer: "foo-1.0_1"}})
+	sign()
+	r, err := New(dir, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RepodataKey = &PublicKey{Minisign: priv.Public()}
+	h, err := NewHandle(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tampered repository data keeps the current repository
+	replaceRepodata(t, dir, map[string]Package{"foo": {PkgVer: "foo-1.0_2"}})
+	if _, err := h.Reload(); !errors.Is(err, minisign.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if h.Repository().Index["foo"].PkgVer != "foo-1.0_1" {
+		t.Fatal("repository was replaced by tampered repodata")
+	}
+
+	sign()
+	if ok, err := h.Reload(); !ok || err != nil {
+		t.Fatalf("expected reload, got %v, %v", ok, err)
+	}
+	if h.Repository().Index["foo"].PkgVer != "foo-1.0_2" || h.Repository().RepodataKey == nil {
+		t.Fatalf("unexpected repository %+v", h.Repository())
+	}
+}
@@ -0,0 +1,355 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// indexCacheMagic identifies the index cache format and its version
+const indexCacheMagic = "XBPSRIDX\x01"
+
+// ErrIndexCache is returned for index caches that are invalid or do not
+// match the repository data.
+var ErrIndexCache = errors.New("invalid or stale index cache")
+
+// The index cache is an uncompressed file of:
+//
+//	magic
+//	sha256 of the repository data
+//	uvarint number of strings, followed by the length prefixed strings
+//	uvarint number of package fields, followed by the field name strings
+//	meta, index and stage
+//
+// All strings are stored once in the string table and referenced by
+// their uvarint index. Meta is a byte 1 followed by the length prefixed
+// key, uvarint size and signed by string or a byte 0 if there is none.
+// Index and stage are the uvarint number of packages plus one, zero for
+// nil, followed by the name and fields of each package in field order.
+// Lists and maps are prefixed with their length plus one as well.
+
+// packageFields are the fields of Package in encoding order
+var packageFields = func() []reflect.StructField {
+	var res []reflect.StructField
+	for _, f := range reflect.VisibleFields(reflect.TypeOf(Package{})) {
+		if f.IsExported() {
+			res = append(res, f)
+		}
+	}
+	return res
+}()
+
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("plist"), ",")
+	return name
+}
+
+type cacheWriter struct {
+	body    bytes.Buffer
+	strings []string
+	ids     map[string]uint64
+	buf     [binary.MaxVarintLen64]byte
+}
+
+func (w *cacheWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	w.body.Write(w.buf[:n])
+}
+
+func (w *cacheWriter) string(s string) {
+	id, ok := w.ids[s]
+	if !ok {
+		id = uint64(len(w.strings))
+		w.ids[s] = id
+		w.strings = append(w.strings, s)
+	}
+	w.uvarint(id)
+}
+
+func (w *cacheWriter) value(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		w.string(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			w.body.WriteByte(1)
+		} else {
+			w.body.WriteByte(0)
+		}
+	case reflect.Int64:
+		n := binary.PutVarint(w.buf[:], v.Int())
+		w.body.Write(w.buf[:n])
+	case reflect.Slice:
+		if v.IsNil() {
+			w.uvarint(0)
+			return
+		}
+		w.uvarint(uint64(v.Len()) + 1)
+		for i := 0; i < v.Len(); i++ {
+			w.value(v.Index(i))
+		}
+	case reflect.Struct:
+		for _, f := range packageFields {
+			w.value(v.FieldByIndex(f.Index))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			w.uvarint(0)
+			return
+		}
+		w.uvarint(uint64(v.Len()) + 1)
+		iter := v.MapRange()
+		for iter.Next() {
+			w.value(iter.Key())
+			w.value(iter.Value())
+		}
+	default:
+		panic(fmt.Sprintf("index cache: unsupported kind %s", v.Kind()))
+	}
+}
+
+func (w *cacheWriter) index(index map[string]Package) {
+	w.value(reflect.ValueOf(index))
+}
+
+// writeIndexCache writes the index cache of the repository data with the hash
+func (repo *Repository) writeIndexCache(out io.Writer, sum []byte) error {
+	w := &cacheWriter{ids: make(map[string]uint64)}
+	w.uvarint(uint64(len(packageFields)))
+	for _, f := range packageFields {
+		w.string(fieldName(f))
+	}
+	if m := repo.Meta; m != nil {
+		w.body.WriteByte(1)
+		w.uvarint(uint64(len(m.Key)))
+		w.body.Write(m.Key)
+		w.uvarint(uint64(m.Size))
+		w.string(m.SignedBy)
+	} else {
+		w.body.WriteByte(0)
+	}
+	w.index(repo.Index)
+	w.index(repo.Stage)
+
+	var hdr bytes.Buffer
+	hdr.WriteString(indexCacheMagic)
+	hdr.Write(sum)
+	n := binary.PutUvarint(w.buf[:], uint64(len(w.strings)))
+	hdr.Write(w.buf[:n])
+	for _, s := range w.strings {
+		n := binary.PutUvarint(w.buf[:], uint64(len(s)))
+		hdr.Write(w.buf[:n])
+		hdr.WriteString(s)
+	}
+	if _, err := out.Write(hdr.Bytes()); err != nil {
+		return err
+	}
+	_, err := out.Write(w.body.Bytes())
+	return err
+}
+
+type cacheReader struct {
+	buf     []byte
+	strings []string
+	err     error
+}
+
+func (r *cacheReader) fail() {
+	if r.err == nil {
+		r.err = ErrIndexCache
+	}
+	r.buf = nil
+}
+
+func (r *cacheReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *cacheReader) bytes(n uint64) []byte {
+	if uint64(len(r.buf)) < n {
+		r.fail()
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *cacheReader) string() string {
+	id := r.uvarint()
+	if id >= uint64(len(r.strings)) {
+		r.fail()
+		return ""
+	}
+	return r.strings[id]
+}
+
+// length returns the length of a list or map and false for nil
+func (r *cacheReader) length() (int, bool) {
+	n := r.uvarint()
+	if n == 0 || n-1 > uint64(len(r.buf)) {
+		if n != 0 {
+			r.fail()
+		}
+		return 0, false
+	}
+	return int(n - 1), true
+}
+
+func (r *cacheReader) value(v reflect.Value) {
+	if r.err != nil {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(r.string())
+	case reflect.Bool:
+		if b := r.bytes(1); b != nil {
+			v.SetBool(b[0] != 0)
+		}
+	case reflect.Int64:
+		i, n := binary.Varint(r.buf)
+		if n <= 0 {
+			r.fail()
+			return
+		}
+		r.buf = r.buf[n:]
+		v.SetInt(i)
+	case reflect.Slice:
+		n, ok := r.length()
+		if !ok {
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n && r.err == nil; i++ {
+			r.value(s.Index(i))
+		}
+		v.Set(s)
+	case reflect.Struct:
+		for _, f := range packageFields {
+			r.value(v.FieldByIndex(f.Index))
+		}
+	case reflect.Map:
+		n, ok := r.length()
+		if !ok {
+			return
+		}
+		m := reflect.MakeMapWithSize(v.Type(), n)
+		key := reflect.New(v.Type().Key()).Elem()
+		for i := 0; i < n && r.err == nil; i++ {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			r.value(key)
+			r.value(elem)
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+	}
+}
+
+// readIndexCache reads the index cache if it matches the hash
+func (repo *Repository) readIndexCache(buf []byte, sum []byte) error {
+	if !bytes.HasPrefix(buf, []byte(indexCacheMagic)) {
+		return ErrIndexCache
+	}
+	buf = buf[len(indexCacheMagic):]
+	if len(buf) < len(sum) || !bytes.Equal(buf[:len(sum)], sum) {
+		return ErrIndexCache
+	}
+	r := &cacheReader{buf: buf[len(sum):]}
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		return ErrIndexCache
+	}
+	r.strings = make([]string, n)
+	for i := range r.strings {
+		r.strings[i] = string(r.bytes(r.uvarint()))
+	}
+	// the cache is stale if the package fields changed
+	if n := r.uvarint(); n != uint64(len(packageFields)) {
+		return ErrIndexCache
+	}
+	for _, f := range packageFields {
+		if r.string() != fieldName(f) {
+			return ErrIndexCache
+		}
+	}
+	var meta *Meta
+	if b := r.bytes(1); b != nil && b[0] == 1 {
+		meta = &Meta{}
+		meta.Key = append([]byte(nil), r.bytes(r.uvarint())...)
+		meta.Size = uint16(r.uvarint())
+		meta.SignedBy = r.string()
+	}
+	var index, stage map[string]Package
+	r.value(reflect.ValueOf(&index).Elem())
+	r.value(reflect.ValueOf(&stage).Elem())
+	if r.err != nil || len(r.buf) != 0 {
+		return ErrIndexCache
+	}
+	repo.Meta, repo.Index, repo.Stage = meta, index, stage
+	return nil
+}
+
+// IndexCachePath returns the path of the index cache, next to the cached
+// repository data or the local repository data if CacheDir is empty.
+func (repo *Repository) IndexCachePath() (string, error) {
+	cachedir := repo.CacheDir
+	if cachedir != "" && !repo.URI.IsRemote() {
+		return filepath.Join(cachedir, repo.URI.CacheString(), repodataName(repo.Arch)+".idx"), nil
+	}
+	repodata, err := repo.URI.Repodata(repo.Arch, cachedir)
+	if err != nil {
+		return "", err
+	}
+	return repodata + ".idx", nil
+}
+
+// readCached reads the repository data from the index cache if it matches
+// the repository data and otherwise decodes the repository data and
+// replaces the index cache.
+func (repo *Repository) readCached(rd io.Reader) error {
+	buf, err := io.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(buf)
+	path, err := repo.IndexCachePath()
+	if err != nil {
+		return err
+	}
+	if cache, err := os.ReadFile(path); err == nil && repo.readIndexCache(cache, hash[:]) == nil {
+		return nil
+	}
+	if _, err := repo.ReadFrom(bytes.NewReader(buf)); err != nil {
+		return err
+	}
+	// the cache is an optimization, failing to write it is not an error
+	_ = repo.saveIndexCache(path, hash[:])
+	return nil
+}
+
+// saveIndexCache atomically replaces the index cache at path
+func (repo *Repository) saveIndexCache(path string, sum []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := repo.writeIndexCache(&buf, sum); err != nil {
+		return err
+	}
+	return WriteFile(path, &buf, time.Time{})
+}
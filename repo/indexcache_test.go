@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexCache(t *testing.T) {
+	dir := t.TempDir()
+	index := map[string]Package{
+		"foo": {
+			PkgVer:       "foo-1.0_1",
+			Architecture: "x86_64",
+			RunDepends:   []string{"libbar>=1.0_1", "glibc>=2.36_1"},
+			Alternatives: map[string][]string{"sh": {"/bin/sh:/bin/foo"}},
+			FilenameSize: 1024,
+			Preserve:     true,
+		},
+		"libbar": {PkgVer: "libbar-1.0_1", Architecture: "x86_64", ShlibRequires: []string{"libc.so.6"}},
+	}
+	writeRepodata(t, filepath.Join(dir, "x86_64-repodata"), index)
+
+	open := func(cache bool) *Repository {
+		t.Helper()
+		r, err := New(dir, "x86_64")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.CacheDir = filepath.Join(dir, "cache")
+		r.IndexCache = cache
+		if err := r.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	// compare with the decoded plist, which does not keep empty values
+	index = open(false).Index
+	r := open(true)
+	if !reflect.DeepEqual(r.Index, index) {
+		t.Fatalf("expected index %+v, got %+v", index, r.Index)
+	}
+	path, err := r.IndexCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("index cache was not written: %v", err)
+	}
+	buf, err := os.ReadFile(filepath.Join(dir, "x86_64-repodata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf)
+	var cached Repository
+	if err := cached.readIndexCache(cache, sum[:]); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cached.Index, index) || cached.Stage != nil || cached.Meta != nil {
+		t.Fatalf("unexpected cached repository %+v", cached)
+	}
+	if !reflect.DeepEqual(open(true).Index, index) {
+		t.Fatal("repository read from cache does not match")
+	}
+
+	// stale and corrupt caches are not used
+	other := sha256.Sum256([]byte("other"))
+	if err := cached.readIndexCache(cache, other[:]); !errors.Is(err, ErrIndexCache) {
+		t.Fatalf("expected ErrIndexCache for stale cache, got %v", err)
+	}
+	if err := cached.readIndexCache(cache[:len(cache)-3], sum[:]); !errors.Is(err, ErrIndexCache) {
+		t.Fatalf("expected ErrIndexCache for truncated cache, got %v", err)
+	}
+	if err := os.WriteFile(path, cache[:len(cache)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(open(true).Index, index) {
+		t.Fatal("repository read with corrupt cache does not match")
+	}
+	writeRepodata(t, filepath.Join(dir, "x86_64-repodata"), map[string]Package{"foo": {PkgVer: "foo-1.1_1"}})
+	if !reflect.DeepEqual(open(true).Index, open(false).Index) {
+		t.Fatal("stale cache was used")
+	}
+	if cache, err := os.ReadFile(path); err != nil || bytes.Contains(cache, []byte("libbar")) {
+		t.Fatal("stale cache was not replaced")
+	}
+}
@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SchemaVersion is the version of the JSON representation of repositories.
+//
+// The representation is an object with the keys:
+//
+//	schema-version  the schema version, currently 1
+//	arch            the repository architecture
+//	meta            the legacy public key with public-key (base64),
+//	                public-key-size and signature-by
+//	index           package names mapped to packages
+//	stage           package names mapped to staged packages
+//
+// Packages use the xbps property names as keys, like pkgver and run_depends,
+// keys of empty properties are omitted. New keys may be added without
+// changing the schema version.
+const SchemaVersion = 1
+
+type jsonRepository struct {
+	SchemaVersion int                `json:"schema-version"`
+	Arch          string             `json:"arch,omitempty"`
+	Meta          *Meta              `json:"meta,omitempty"`
+	Index         map[string]Package `json:"index"`
+	Stage         map[string]Package `json:"stage,omitempty"`
+}
+
+// MarshalJSON returns the JSON representation of the repository
+func (repo *Repository) MarshalJSON() ([]byte, error) {
+	index := repo.Index
+	if index == nil {
+		index = map[string]Package{}
+	}
+	return marshal(jsonRepository{
+		SchemaVersion: SchemaVersion,
+		Arch:          repo.Arch,
+		Meta:          repo.Meta,
+		Index:         index,
+		Stage:         repo.Stage,
+	})
+}
+
+// marshal is json.Marshal without escaping HTML characters, which are
+// common in dependency patterns and maintainer addresses.
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON reads the architecture and repository data from its JSON
+// representation.
+func (repo *Repository) UnmarshalJSON(buf []byte) error {
+	var v jsonRepository
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	if v.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported repository schema version %d", v.SchemaVersion)
+	}
+	repo.Arch = v.Arch
+	repo.Meta = v.Meta
+	repo.Index = v.Index
+	repo.Stage = v.Stage
+	return nil
+}
+
+// WriteYAML writes the JSON representation of the repository as YAML
+func (repo *Repository) WriteYAML(w io.Writer) error {
+	buf, err := repo.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	out.WriteString("---\n")
+	writeYAML(&out, v, 0)
+	_, err = w.Write(out.Bytes())
+	return err
+}
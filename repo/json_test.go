@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testJSONRepository() *Repository {
+	return &Repository{
+		Arch: "x86_64",
+		Meta: &Meta{Key: []byte("key"), Size: 4096, SignedBy: "Foo <foo@example.org>"},
+		Index: map[string]Package{
+			"foo": {
+				PkgVer:       "foo-1.0_1",
+				Architecture: "x86_64",
+				RunDepends:   []string{"libbar>=1.0_1"},
+				Alternatives: map[string][]string{"sh": {"/bin/sh:/bin/foo"}},
+				FilenameSize: 1024,
+				Preserve:     true,
+			},
+		},
+		Stage: map[string]Package{
+			"libbar": {PkgVer: "libbar-2.0_1", ShortDesc: "yes: \"quoted\""},
+		},
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r := testJSONRepository()
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.TrimSpace(out.Bytes())
+	expect := `{"schema-version":1,"arch":"x86_64","meta":{"public-key":"a2V5","public-key-size":4096,"signature-by":"Foo <foo@example.org>"},` +
+		`"index":{"foo":{"alternatives":{"sh":["/bin/sh:/bin/foo"]},"architecture":"x86_64","filename-size":1024,"pkgver":"foo-1.0_1","preserve":true,"run_depends":["libbar>=1.0_1"]}},` +
+		`"stage":{"libbar":{"pkgver":"libbar-2.0_1","short_desc":"yes: \"quoted\""}}}`
+	if string(buf) != expect {
+		t.Fatalf("unexpected JSON:\n%s", buf)
+	}
+	var got Repository
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, r) {
+		t.Fatalf("expected %+v, got %+v", r, &got)
+	}
+	if err := json.Unmarshal([]byte(`{"schema-version":2}`), &got); err == nil {
+		t.Fatal("expected error for unsupported schema version")
+	}
+
+	// imported repositories encode to repodata
+	var repodata bytes.Buffer
+	if _, err := got.WriteTo(&repodata); err != nil {
+		t.Fatal(err)
+	}
+	var read Repository
+	if _, err := read.ReadFrom(&repodata); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Index, r.Index) || !reflect.DeepEqual(read.Stage, r.Stage) {
+		t.Fatalf("repodata does not match imported repository: %+v", read)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testJSONRepository().WriteYAML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := strings.Join([]string{
+		"---",
+		"arch: \"x86_64\"",
+		"index:",
+		"  foo:",
+		"    alternatives:",
+		"      sh:",
+		"        - \"/bin/sh:/bin/foo\"",
+		"    architecture: \"x86_64\"",
+		"    filename-size: 1024",
+		"    pkgver: \"foo-1.0_1\"",
+		"    preserve: true",
+		"    run_depends:",
+		"      - \"libbar>=1.0_1\"",
+		"meta:",
+		"  public-key: \"a2V5\"",
+		"  public-key-size: 4096",
+		"  signature-by: \"Foo <foo@example.org>\"",
+		"schema-version: 1",
+		"stage:",
+		"  libbar:",
+		"    pkgver: \"libbar-2.0_1\"",
+		"    short_desc: \"yes: \\\"quoted\\\"\"",
+		"",
+	}, "\n")
+	if buf.String() != expect {
+		t.Fatalf("unexpected YAML:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// ErrUntrusted is returned if a public key is not in the key store.
+var ErrUntrusted = errors.New("public key is not trusted")
+
+// KeyStore is the directory of trusted public keys, usually <dbdir>/keys.
+type KeyStore struct {
+	Dir string
+}
+
+// NewKeyStore returns the key store xbps uses for dbdir
+func NewKeyStore(dbdir string) *KeyStore {
+	return &KeyStore{Dir: path.Join(dbdir, "keys")}
+}
+
+// Lookup reads the public key with the legacy or SHA256 fingerprint from
+// the key store.
+func (ks *KeyStore) Lookup(fingerprint string) (*PublicKey, error) {
+	if strings.HasPrefix(fingerprint, "SHA256:") {
+		// unreadable keys are only reported if the key was not found
+		keys, err := ks.Keys()
+		for _, key := range keys {
+			if key.FingerprintSHA256() == fingerprint {
+				return key, nil
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", fingerprint, ErrUntrusted, err)
+		}
+		return nil, fmt.Errorf("%s: %w", fingerprint, ErrUntrusted)
+	}
+	buf, err := os.ReadFile(path.Join(ks.Dir, fmt.Sprintf("%s.plist", fingerprint)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", fingerprint, ErrUntrusted)
+		}
+		return nil, err
+	}
+	key := &PublicKey{}
+	if err := ParsePublicKey(buf, key); err != nil {
+		return nil, fmt.Errorf("%s: %w", fingerprint, err)
+	}
+	return key, nil
+}
+
+// Trusted returns nil if key is in the key store, otherwise an error wrapping ErrUntrusted
+func (ks *KeyStore) Trusted(key *PublicKey) error {
+	stored, err := ks.Lookup(key.Fingerprint())
+	if err != nil {
+		if errors.Is(err, ErrUntrusted) {
+			return fmt.Errorf("%s (%s): %w", key.Fingerprint(), key.FingerprintSHA256(), ErrUntrusted)
+		}
+		return err
+	}
+	if !stored.Equal(key) {
+		return fmt.Errorf("%s (%s): key does not match stored key: %w", key.Fingerprint(), key.FingerprintSHA256(), ErrUntrusted)
+	}
+	return nil
+}
+
+// Keys returns the public keys in the key store sorted by file name,
+// files that cannot be read are skipped and returned joined in the error.
+func (ks *KeyStore) Keys() ([]*PublicKey, error) {
+	files, err := filepath.Glob(filepath.Join(ks.Dir, "*.plist"))
+	if err != nil {
+		return nil, err
+	}
+	var keys []*PublicKey
+	var errs []error
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		key := &PublicKey{}
+		if err := ParsePublicKey(buf, key); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, errors.Join(errs...)
+}
+
+// Add writes the public key to the key store
+func (ks *KeyStore) Add(key *PublicKey) error {
+	buf, err := plist.MarshalIndent(key, plist.XMLFormat, "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ks.Dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(ks.Dir, key.Filename()), buf, 0o644)
+}
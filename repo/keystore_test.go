@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+)
+
+func TestKeyStore(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &PublicKey{Key: &priv.PublicKey, Size: 1024, SignedBy: "Test <test@example.org>"}
+	ks := &KeyStore{Dir: t.TempDir()}
+	if err := ks.Trusted(key); !errors.Is(err, ErrUntrusted) || !strings.Contains(err.Error(), key.FingerprintSHA256()) {
+		t.Fatalf("expected ErrUntrusted with SHA256 fingerprint, got %v", err)
+	}
+	if err := ks.Add(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Trusted(key); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := ks.Lookup(key.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.SignedBy != key.SignedBy || stored.Size != key.Size || !stored.Key.Equal(key.Key) {
+		t.Fatalf("stored key %v does not match %v", stored, key)
+	}
+	// a broken key does not prevent looking up the other keys
+	bogus := filepath.Join(ks.Dir, "00:bogus.plist")
+	if err := os.WriteFile(bogus, []byte("bogus"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fp := key.FingerprintSHA256()
+	if !strings.HasPrefix(fp, "SHA256:") || !key.MatchFingerprint(fp) || !key.MatchFingerprint(key.Fingerprint()) {
+		t.Fatalf("unexpected SHA256 fingerprint %q", fp)
+	}
+	stored, err = ks.Lookup(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Key.Equal(key.Key) {
+		t.Fatalf("key looked up by SHA256 fingerprint %v does not match %v", stored, key)
+	}
+	if _, err := ks.Lookup("SHA256:unknown"); !errors.Is(err, ErrUntrusted) || !strings.Contains(err.Error(), bogus) {
+		t.Fatalf("expected ErrUntrusted reporting the broken key, got %v", err)
+	}
+}
+
+func TestKeyStoreMinisign(t *testing.T) {
+	priv, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &PublicKey{Minisign: priv.Public(), Size: 256, SignedBy: "Test <test@example.org>"}
+	ks := &KeyStore{Dir: t.TempDir()}
+	if err := ks.Add(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Trusted(key); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := ks.Lookup(key.FingerprintSHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Equal(key) || stored.Key != nil || stored.SignatureExt() != minisign.Ext {
+		t.Fatalf("stored key %+v does not match %+v", stored, key)
+	}
+	other, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Trusted(&PublicKey{Minisign: other.Public()}); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("expected ErrUntrusted, got %v", err)
+	}
+}
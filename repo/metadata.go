@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/version"
+)
+
+// ErrMalformed is returned for malformed package metadata
+var ErrMalformed = errors.New("malformed package metadata")
+
+// BuildDateLayout is the time layout of the build-date property
+const BuildDateLayout = "2006-01-02 15:04 MST"
+
+// SourceRevision is the source package and template repository commit
+// a package was built from.
+type SourceRevision struct {
+	SourcePkg string `json:"sourcepkg"`
+	Commit    string `json:"commit"`
+}
+
+func (r SourceRevision) String() string {
+	return r.SourcePkg + ":" + r.Commit
+}
+
+// ParsePkgVer parses the pkgver of the package and its version
+func (p *Package) ParsePkgVer() (pkgver.PkgVer, version.Version, error) {
+	pv, err := pkgver.Parse(p.PkgVer)
+	if err != nil {
+		return pv, version.Version{}, fmt.Errorf("pkgver %q: %w", p.PkgVer, ErrMalformed)
+	}
+	if pv.Version == "" || pv.Pattern != "" {
+		return pv, version.Version{}, fmt.Errorf("pkgver %q: missing version: %w", p.PkgVer, ErrMalformed)
+	}
+	return pv, version.Parse(pv.Version), nil
+}
+
+// ParseBuildDate parses the build date of the package
+func (p *Package) ParseBuildDate() (time.Time, error) {
+	t, err := time.Parse(BuildDateLayout, p.BuildDate)
+	if err != nil {
+		return t, fmt.Errorf("build-date %q: %w", p.BuildDate, ErrMalformed)
+	}
+	return t, nil
+}
+
+// ParseSourceRevisions parses the source revisions of the package
+func (p *Package) ParseSourceRevisions() ([]SourceRevision, error) {
+	var res []SourceRevision
+	for _, f := range strings.Fields(p.SourceRevisions) {
+		pkg, commit, ok := strings.Cut(f, ":")
+		if !ok || pkg == "" || commit == "" {
+			return nil, fmt.Errorf("source-revisions %q: %w", p.SourceRevisions, ErrMalformed)
+		}
+		res = append(res, SourceRevision{SourcePkg: pkg, Commit: commit})
+	}
+	return res, nil
+}
+
+// ParseRunDepends parses the run dependencies of the package
+func (p *Package) ParseRunDepends() ([]pkgver.PkgVer, error) {
+	return parsePatterns("run_depends", p.RunDepends)
+}
+
+// ParseConflicts parses the conflicts of the package
+func (p *Package) ParseConflicts() ([]pkgver.PkgVer, error) {
+	return parsePatterns("conflicts", p.Conflicts)
+}
+
+// ParseReplaces parses the replaces of the package
+func (p *Package) ParseReplaces() ([]pkgver.PkgVer, error) {
+	return parsePatterns("replaces", p.Replaces)
+}
+
+func parsePatterns(key string, patterns []string) ([]pkgver.PkgVer, error) {
+	res := make([]pkgver.PkgVer, 0, len(patterns))
+	for _, s := range patterns {
+		pv, err := pkgver.Parse(s)
+		if err != nil || pv.Name == "" {
+			return nil, fmt.Errorf("%s %q: %w", key, s, ErrMalformed)
+		}
+		res = append(res, pv)
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/version"
+)
+
+func TestParseMetadata(t *testing.T) {
+	pkg := Package{
+		PkgVer:          "foo-32bit-1.0_2",
+		BuildDate:       "2023-01-15 10:30 UTC",
+		SourceRevisions: "foo:0123abc",
+		RunDepends:      []string{"libbar>=1.0_1", "glibc"},
+		Conflicts:       []string{"oldfoo<1.0_1"},
+	}
+	pv, v, err := pkg.ParsePkgVer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pv != (pkgver.PkgVer{Name: "foo-32bit", Version: "1.0_2"}) || v.Cmp(version.Parse("1.0_1")) <= 0 {
+		t.Errorf("unexpected pkgver %#v", pv)
+	}
+	date, err := pkg.ParseBuildDate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := time.Date(2023, 1, 15, 10, 30, 0, 0, time.UTC); !date.Equal(expect) {
+		t.Errorf("expected build date %v, got %v", expect, date)
+	}
+	revs, err := pkg.ParseSourceRevisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []SourceRevision{{SourcePkg: "foo", Commit: "0123abc"}}; !reflect.DeepEqual(revs, expect) {
+		t.Errorf("expected source revisions %v, got %v", expect, revs)
+	}
+	deps, err := pkg.ParseRunDepends()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []pkgver.PkgVer{{Name: "libbar", Pattern: ">=1.0_1"}, {Name: "glibc"}}; !reflect.DeepEqual(deps, expect) {
+		t.Errorf("expected run dependencies %v, got %v", expect, deps)
+	}
+	conflicts, err := pkg.ParseConflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []pkgver.PkgVer{{Name: "oldfoo", Pattern: "<1.0_1"}}; !reflect.DeepEqual(conflicts, expect) {
+		t.Errorf("expected conflicts %v, got %v", expect, conflicts)
+	}
+	if replaces, err := pkg.ParseReplaces(); err != nil || len(replaces) != 0 {
+		t.Errorf("expected no replaces, got %v, %v", replaces, err)
+	}
+}
+
+func TestParseMetadataMalformed(t *testing.T) {
+	pkg := Package{
+		PkgVer:          "foo",
+		BuildDate:       "yesterday",
+		SourceRevisions: "foo",
+		Replaces:        []string{"foo>="},
+	}
+	if _, _, err := pkg.ParsePkgVer(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("pkgver: expected ErrMalformed, got %v", err)
+	}
+	if _, err := pkg.ParseBuildDate(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("build-date: expected ErrMalformed, got %v", err)
+	}
+	if _, err := pkg.ParseSourceRevisions(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("source-revisions: expected ErrMalformed, got %v", err)
+	}
+	if _, err := pkg.ParseReplaces(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("replaces: expected ErrMalformed, got %v", err)
+	}
+}
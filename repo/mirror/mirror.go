@@ -0,0 +1,204 @@
+// Package mirror implements mirroring a repository to a local directory.
+//
+// A sync keeps the mirror consistent at all times: the new repository data
+// is downloaded and validated first, then all new or changed packages are
+// downloaded and verified against the index and their signatures, only then
+// the repository data is published and files that are no longer referenced
+// by any repository data in the directory are deleted.
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/repo/cache"
+	"github.com/Duncaen/go-xbps/repo/uri"
+)
+
+// Mirror mirrors a source repository to a directory
+type Mirror struct {
+	// Source is the repository to mirror
+	Source *uri.URI
+	// Mirrors are optional mirrors of the source repository
+	Mirrors *uri.Mirrors
+	// Dir is the mirror directory
+	Dir string
+	// Archs are the architectures to mirror
+	Archs []string
+	// Keys is the store of trusted keys, if nil the public key
+	// of the source repository data is trusted.
+	Keys *repo.KeyStore
+	// RepodataKey is the trusted Ed25519 key of the repository data, if
+	// set the repository data is only mirrored with a valid detached
+	// signature, which is published next to it.
+	RepodataKey *repo.PublicKey
+	// Jobs is the number of concurrent downloads
+	Jobs int
+	// Progress is called with the progress of package downloads
+	Progress func(cache.Progress)
+}
+
+// Result is the result of a sync
+type Result struct {
+	// Downloaded are the package files that were downloaded
+	Downloaded []string
+	// Deleted are the files that were deleted
+	Deleted []string
+}
+
+// staged is downloaded repository data that is not published yet
+type staged struct {
+	repo    *repo.Repository
+	data    []byte
+	sig     []byte
+	modTime time.Time
+}
+
+// New returns a mirror of the source repository in dir
+func New(source, dir string, archs ...string) (*Mirror, error) {
+	u, err := uri.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Mirror{Source: u, Dir: dir, Archs: archs}, nil
+}
+
+// Sync updates the mirror
+func (m *Mirror) Sync(ctx context.Context) (*Result, error) {
+	if len(m.Archs) == 0 {
+		return nil, fmt.Errorf("no architectures to mirror")
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := cache.New(m.Dir)
+	c.Keys, c.Jobs, c.Progress = m.Keys, m.Jobs, m.Progress
+
+	var repos []*staged
+	for _, arch := range m.Archs {
+		s, err := m.fetchRepodata(ctx, arch)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, s)
+	}
+
+	res := &Result{}
+	for _, s := range repos {
+		// stage packages are in the repository directory as well
+		for _, r := range []*repo.Repository{s.repo, stage(s.repo)} {
+			paths, err := c.FetchAll(ctx, r, missing(c, r)...)
+			if err != nil {
+				return res, err
+			}
+			res.Downloaded = append(res.Downloaded, paths...)
+		}
+	}
+
+	for _, s := range repos {
+		if err := m.publish(s); err != nil {
+			return res, err
+		}
+	}
+
+	published, err := m.published()
+	if err != nil {
+		return res, err
+	}
+	deleted, err := c.Clean(published...)
+	res.Deleted = deleted
+	return res, err
+}
+
+// fetchRepodata downloads and validates the repository data for arch
+func (m *Mirror) fetchRepodata(ctx context.Context, arch string) (*staged, error) {
+	r := &repo.Repository{URI: m.Source, Mirrors: m.Mirrors, Arch: arch, RepodataKey: m.RepodataKey}
+	f, err := r.Fetch(ctx, fmt.Sprintf("%s-repodata", arch), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to fetch repodata: %w", arch, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to fetch repodata: %w", arch, err)
+	}
+	var sig []byte
+	if r.RepodataKey != nil {
+		if sig, err = r.FetchRepodataSig(ctx); err != nil {
+			return nil, fmt.Errorf("%s: %w", arch, err)
+		}
+		if err := r.VerifyRepodata(bytes.NewReader(data), sig); err != nil {
+			return nil, fmt.Errorf("%s: %w", arch, err)
+		}
+	}
+	if _, err := r.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%s: invalid repodata: %w", arch, err)
+	}
+	return &staged{repo: r, data: data, sig: sig, modTime: f.ModTime}, nil
+}
+
+// stage returns a repository with the staged packages as index
+func stage(r *repo.Repository) *repo.Repository {
+	return &repo.Repository{URI: r.URI, Mirrors: r.Mirrors, Arch: r.Arch, Meta: r.Meta, Index: r.Stage}
+}
+
+// missing returns the sorted names of packages that are not mirrored or
+// do not verify, errors like untrusted keys are reported by the download.
+func missing(c *cache.Cache, r *repo.Repository) []string {
+	var names []string
+	for name, pkg := range r.Index {
+		if err := c.Verify(r, &pkg); err != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// publish atomically replaces the repository data and its signature in the
+// mirror, a signature of previous repository data is removed.
+func (m *Mirror) publish(s *staged) error {
+	path := filepath.Join(m.Dir, fmt.Sprintf("%s-repodata", s.repo.Arch))
+	if s.sig != nil {
+		if err := repo.WriteFile(path+minisign.Ext, bytes.NewReader(s.sig), s.modTime); err != nil {
+			return err
+		}
+	} else if err := os.Remove(path + minisign.Ext); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return repo.WriteFile(path, bytes.NewReader(s.data), s.modTime)
+}
+
+// published returns all repository data in the mirror directory,
+// including architectures that are not synced.
+func (m *Mirror) published() ([]*repo.Repository, error) {
+	matches, err := filepath.Glob(filepath.Join(m.Dir, "*-repodata"))
+	if err != nil {
+		return nil, err
+	}
+	var repos []*repo.Repository
+	for _, path := range matches {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r := &repo.Repository{}
+		_, err = r.ReadFrom(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		repos = append(repos, r)
+	}
+	return repos, nil
+}
@@ -0,0 +1,196 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/internal/repotest"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+type upstream struct {
+	*repotest.Repo
+	mu    sync.Mutex
+	paths []string
+}
+
+func newUpstream(t *testing.T) *upstream {
+	t.Helper()
+	up := &upstream{Repo: repotest.New(t, "x86_64")}
+	up.Hook = func(r *http.Request) {
+		up.mu.Lock()
+		up.paths = append(up.paths, r.URL.Path)
+		up.mu.Unlock()
+	}
+	return up
+}
+
+// add writes a signed package and updates the repository data
+func (up *upstream) add(t *testing.T, pkgver, content string) {
+	t.Helper()
+	up.Add(t, pkgver, "x86_64", content)
+	up.WriteRepodata(t)
+}
+
+func names(paths []string) []string {
+	var names []string
+	for _, p := range paths {
+		names = append(names, filepath.Base(p))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSync(t *testing.T) {
+	up := newUpstream(t)
+	up.add(t, "foo-1.0_1", "foo package")
+	up.add(t, "bar-1.0_1", "bar package")
+	dir := t.TempDir()
+	m, err := New(up.URL, dir, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := m.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(res.Downloaded), " "); got != "bar-1.0_1.x86_64.xbps foo-1.0_1.x86_64.xbps" {
+		t.Fatalf("unexpected downloads %q", got)
+	}
+	for _, name := range []string{"x86_64-repodata", "foo-1.0_1.x86_64.xbps.sig2", "bar-1.0_1.x86_64.xbps.sig2"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the updated package is downloaded, the old one is deleted
+	up.add(t, "foo-1.1_1", "foo package update")
+	up.paths = nil
+	res, err = m.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(res.Downloaded), " "); got != "foo-1.1_1.x86_64.xbps" {
+		t.Fatalf("unexpected downloads %q", got)
+	}
+	if got := strings.Join(names(res.Deleted), " "); got != "foo-1.0_1.x86_64.xbps foo-1.0_1.x86_64.xbps.sig2" {
+		t.Fatalf("unexpected deletions %q", got)
+	}
+	for _, p := range up.paths {
+		if strings.HasPrefix(p, "/bar") {
+			t.Fatalf("unchanged package was downloaded again: %s", p)
+		}
+	}
+
+	// corrupted packages in the mirror are replaced
+	if err := os.WriteFile(filepath.Join(dir, "bar-1.0_1.x86_64.xbps"), []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = m.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(res.Downloaded), " "); got != "bar-1.0_1.x86_64.xbps" {
+		t.Fatalf("unexpected downloads %q", got)
+	}
+}
+
+func TestSyncTampered(t *testing.T) {
+	up := newUpstream(t)
+	up.add(t, "foo-1.0_1", "foo package")
+	dir := t.TempDir()
+	m, err := New(up.URL, dir, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	published, err := os.ReadFile(filepath.Join(dir, "x86_64-repodata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new repository data is not published if a package fails verification
+	up.add(t, "bar-1.0_1", "bar package")
+	if err := os.WriteFile(filepath.Join(up.Dir, "bar-1.0_1.x86_64.xbps"), []byte("evil package"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Sync(context.Background()); err == nil {
+		t.Fatal("expected error for tampered package")
+	}
+	buf, err := os.ReadFile(filepath.Join(dir, "x86_64-repodata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != string(published) {
+		t.Fatal("repository data was published with a tampered package")
+	}
+}
+
+func TestSyncRepodataKey(t *testing.T) {
+	up := newUpstream(t)
+	up.add(t, "foo-1.0_1", "foo package")
+	priv, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	m, err := New(up.URL, dir, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.RepodataKey = &repo.PublicKey{Minisign: priv.Public()}
+	if _, err := m.Sync(context.Background()); err == nil {
+		t.Fatal("expected error for unsigned repodata")
+	}
+	up.SignRepodata(t, priv)
+	if _, err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	published := filepath.Join(dir, "x86_64-repodata")
+	data, err := os.ReadFile(published)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := os.ReadFile(published + minisign.Ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := minisign.Verify(priv.Public(), bytes.NewReader(data), sig); err != nil {
+		t.Fatalf("published signature does not verify: %v", err)
+	}
+
+	// repository data signed by another key is not published
+	other, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	up.add(t, "bar-1.0_1", "bar package")
+	up.SignRepodata(t, other)
+	if _, err := m.Sync(context.Background()); !errors.Is(err, minisign.ErrKeyMismatch) {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if buf, err := os.ReadFile(published); err != nil || !bytes.Equal(buf, data) {
+		t.Fatalf("repository data of another key was published: %v", err)
+	}
+
+	// without a key the stale signature is removed
+	m.RepodataKey = nil
+	if _, err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(published + minisign.Ext); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected stale signature to be removed, got %v", err)
+	}
+}
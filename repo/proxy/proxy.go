@@ -0,0 +1,421 @@
+// Package proxy implements a caching proxy for remote xbps repositories.
+//
+// Clients use the proxy as repository, repository data and packages are
+// fetched from the upstream repository on demand and cached on disk in the
+// layout xbps uses for its cache directory:
+//
+//	<dir>/<uri.CacheString()>/<arch>-repodata
+//	<dir>/<uri.CacheString()>/<pkgver>.<arch>.xbps
+//
+// Repository data is revalidated after TTL, packages are verified against
+// the repository index and signature before they are served. With
+// RepodataKey the repository data is verified with its detached signature,
+// which is cached next to it as <arch>-repodata.minisig.
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/repo/cache"
+	"github.com/Duncaen/go-xbps/repo/uri"
+)
+
+// DefaultTTL is the default time after which repository data is revalidated
+const DefaultTTL = 5 * time.Minute
+
+// StatsPath is the path the proxy serves its statistics as JSON on
+const StatsPath = "/_stats"
+
+// Stats are the cache statistics of the proxy
+type Stats struct {
+	// Requests is the number of requests for repository files
+	Requests int64 `json:"requests"`
+	// Hits is the number of requests served from the cache
+	Hits int64 `json:"hits"`
+	// Misses is the number of requests that were fetched from upstream
+	Misses int64 `json:"misses"`
+	// Revalidations is the number of repository data revalidations
+	Revalidations int64 `json:"revalidations"`
+	// Errors is the number of failed upstream requests
+	Errors int64 `json:"errors"`
+	// BytesServed is the number of bytes served from repository files
+	BytesServed int64 `json:"bytes_served"`
+}
+
+// Proxy is a caching proxy for an upstream repository
+type Proxy struct {
+	// Upstream is the upstream repository URI
+	Upstream *uri.URI
+	// Mirrors are optional mirrors of the upstream repository
+	Mirrors *uri.Mirrors
+	// Dir is the cache directory
+	Dir string
+	// TTL is the time after which repository data is revalidated,
+	// defaults to DefaultTTL.
+	TTL time.Duration
+	// Keys is the store of trusted keys, if nil the public key
+	// of the upstream repository data is trusted.
+	Keys *repo.KeyStore
+	// RepodataKey is the trusted Ed25519 key of the repository data, if
+	// set the repository data must have a valid detached signature, which
+	// is served to clients as well.
+	RepodataKey *repo.PublicKey
+	// Archs are the architectures noarch packages are looked up in,
+	// defaults to the architectures of the cached repository data.
+	Archs []string
+	// ErrorLog logs upstream errors, defaults to the standard logger
+	ErrorLog *log.Logger
+
+	cache *cache.Cache
+
+	mu    sync.Mutex
+	repos map[string]*repodata
+
+	requests, hits, misses, revalidations, errors, bytes atomic.Int64
+}
+
+// repodata is the cached repository data of an architecture
+type repodata struct {
+	mu        sync.Mutex
+	repo      *repo.Repository
+	validated time.Time
+}
+
+// New returns a proxy for the upstream repository that caches in dir
+func New(upstream, dir string) (*Proxy, error) {
+	u, err := uri.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsRemote() {
+		return nil, fmt.Errorf("upstream repository is not remote: %s", upstream)
+	}
+	return &Proxy{Upstream: u, Dir: dir}, nil
+}
+
+// Stats returns the cache statistics
+func (p *Proxy) Stats() Stats {
+	return Stats{
+		Requests:      p.requests.Load(),
+		Hits:          p.hits.Load(),
+		Misses:        p.misses.Load(),
+		Revalidations: p.revalidations.Load(),
+		Errors:        p.errors.Load(),
+		BytesServed:   p.bytes.Load(),
+	}
+}
+
+// cacheDir returns the cache directory of the upstream repository
+func (p *Proxy) cacheDir() string {
+	return filepath.Join(p.Dir, p.Upstream.CacheString())
+}
+
+func (p *Proxy) packageCache() *cache.Cache {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cache == nil {
+		p.cache = cache.New(p.cacheDir())
+		p.cache.Keys = p.Keys
+	}
+	return p.cache
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	if name == StatsPath {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p.Stats())
+		return
+	}
+	name = name[1:]
+	if name == "" || strings.Contains(name, "/") || name[0] == '.' {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case strings.HasSuffix(name, "-repodata"+minisign.Ext):
+		p.requests.Add(1)
+		p.serveRepodataSig(w, r, strings.TrimSuffix(name, "-repodata"+minisign.Ext))
+	case strings.HasSuffix(name, "-repodata"):
+		p.requests.Add(1)
+		p.serveRepodata(w, r, strings.TrimSuffix(name, "-repodata"))
+	case strings.HasSuffix(name, ".xbps"):
+		p.requests.Add(1)
+		p.servePackage(w, r, name, "")
+	case strings.HasSuffix(name, ".xbps.sig2"):
+		p.requests.Add(1)
+		p.servePackage(w, r, strings.TrimSuffix(name, ".sig2"), ".sig2")
+	case strings.HasSuffix(name, ".xbps.minisig"):
+		p.requests.Add(1)
+		p.servePackage(w, r, strings.TrimSuffix(name, ".minisig"), ".minisig")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// repository returns the repository data for arch, revalidating it if the TTL expired.
+func (p *Proxy) repository(ctx context.Context, arch string) (*repo.Repository, bool, error) {
+	p.mu.Lock()
+	if p.repos == nil {
+		p.repos = make(map[string]*repodata)
+	}
+	rd, ok := p.repos[arch]
+	if !ok {
+		rd = &repodata{}
+		p.repos[arch] = rd
+	}
+	p.mu.Unlock()
+
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	ttl := p.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if rd.repo != nil && time.Since(rd.validated) < ttl {
+		return rd.repo, true, nil
+	}
+	r := &repo.Repository{URI: p.Upstream, Mirrors: p.Mirrors, Arch: arch, CacheDir: p.Dir, RepodataKey: p.RepodataKey}
+	hit, err := p.revalidate(ctx, r)
+	if err != nil {
+		p.errors.Add(1)
+		if rd.repo != nil {
+			// keep serving the stale repository data
+			return rd.repo, true, nil
+		}
+		// fall back to previously cached repository data
+		if err := r.Open(); err != nil {
+			return nil, false, err
+		}
+		hit = true
+	} else if err := r.Open(); err != nil {
+		return nil, false, err
+	}
+	rd.repo, rd.validated = r, time.Now()
+	return r, hit, nil
+}
+
+// revalidate syncs the repository data if the upstream repository data changed
+// and returns true if the cached repository data was still valid.
+func (p *Proxy) revalidate(ctx context.Context, r *repo.Repository) (bool, error) {
+	p.revalidations.Add(1)
+	cached, err := p.Upstream.Repodata(r.Arch, p.Dir)
+	if err != nil {
+		return false, err
+	}
+	if fi, err := os.Stat(cached); err == nil && p.hasSig(cached) {
+		name := fmt.Sprintf("%s-repodata", r.Arch)
+		var up fs.FileInfo
+		stat := func(u *uri.URI) error {
+			var err error
+			up, err = u.Stat(ctx, name)
+			return err
+		}
+		if p.Mirrors != nil {
+			err = p.Mirrors.Do(ctx, stat)
+		} else {
+			err = stat(p.Upstream)
+		}
+		if err == nil && !up.ModTime().IsZero() && up.ModTime().Equal(fi.ModTime()) {
+			return true, nil
+		}
+	}
+	return false, r.Sync(ctx)
+}
+
+func (p *Proxy) serveRepodata(w http.ResponseWriter, r *http.Request, arch string) {
+	_, hit, err := p.repository(r.Context(), arch)
+	if err != nil {
+		p.serveError(w, r, err)
+		return
+	}
+	p.count(hit)
+	path, err := p.Upstream.Repodata(arch, p.Dir)
+	if err != nil {
+		p.serveError(w, r, err)
+		return
+	}
+	p.serveFile(w, r, path, "no-cache")
+}
+
+// serveRepodataSig serves the detached signature of the repository data,
+// which was fetched and verified together with the cached repository data.
+func (p *Proxy) serveRepodataSig(w http.ResponseWriter, r *http.Request, arch string) {
+	if p.RepodataKey == nil {
+		// without a key the signature could not be verified
+		http.NotFound(w, r)
+		return
+	}
+	_, hit, err := p.repository(r.Context(), arch)
+	if err != nil {
+		p.serveError(w, r, err)
+		return
+	}
+	p.count(hit)
+	path, err := p.Upstream.Repodata(arch, p.Dir)
+	if err != nil {
+		p.serveError(w, r, err)
+		return
+	}
+	p.serveFile(w, r, path+minisign.Ext, "no-cache")
+}
+
+// hasSig returns false if the signature of the cached repository data is
+// missing although it is required.
+func (p *Proxy) hasSig(cached string) bool {
+	if p.RepodataKey == nil {
+		return true
+	}
+	_, err := os.Stat(cached + minisign.Ext)
+	return err == nil
+}
+
+// lookup finds the package file in the repository data of the architecture
+// of the package, noarch packages are looked up in all architectures.
+func (p *Proxy) lookup(ctx context.Context, filename string) (*repo.Repository, string, error) {
+	base := strings.TrimSuffix(filename, ".xbps")
+	i := strings.LastIndexByte(base, '.')
+	if i == -1 {
+		return nil, "", fs.ErrNotExist
+	}
+	pv, err := pkgver.Parse(base[:i])
+	if err != nil {
+		return nil, "", fs.ErrNotExist
+	}
+	name, arch := pv.Name, base[i+1:]
+	archs := []string{arch}
+	if arch == "noarch" {
+		archs = p.archs()
+	}
+	for _, a := range archs {
+		r, _, err := p.repository(ctx, a)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, "", err
+		}
+		if pkg, ok := r.Index[name]; ok && pkg.Filename() == filename {
+			return r, name, nil
+		}
+	}
+	return nil, "", fs.ErrNotExist
+}
+
+// archs returns the configured architectures or the architectures of the
+// loaded and cached repository data.
+func (p *Proxy) archs() []string {
+	if len(p.Archs) > 0 {
+		return p.Archs
+	}
+	seen := make(map[string]bool)
+	p.mu.Lock()
+	for a := range p.repos {
+		seen[a] = true
+	}
+	p.mu.Unlock()
+	matches, _ := filepath.Glob(filepath.Join(p.cacheDir(), "*-repodata"))
+	for _, m := range matches {
+		seen[strings.TrimSuffix(filepath.Base(m), "-repodata")] = true
+	}
+	archs := make([]string, 0, len(seen))
+	for a := range seen {
+		archs = append(archs, a)
+	}
+	sort.Strings(archs)
+	return archs
+}
+
+func (p *Proxy) servePackage(w http.ResponseWriter, r *http.Request, filename, suffix string) {
+	rp, name, err := p.lookup(r.Context(), filename)
+	if err != nil {
+		p.serveError(w, r, err)
+		return
+	}
+	path, hit, err := p.packageCache().FetchCached(r.Context(), rp, name)
+	if err != nil {
+		p.errors.Add(1)
+		p.serveError(w, r, err)
+		return
+	}
+	p.count(hit)
+	p.serveFile(w, r, path+suffix, "public, max-age=86400")
+}
+
+func (p *Proxy) count(hit bool) {
+	if hit {
+		p.hits.Add(1)
+	} else {
+		p.misses.Add(1)
+	}
+}
+
+func (p *Proxy) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, cache.ErrNotFound):
+		http.NotFound(w, r)
+	default:
+		// upstream errors may contain internal details
+		p.logf("%s: %v", r.URL.Path, err)
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+	}
+}
+
+func (p *Proxy) logf(format string, args ...interface{}) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// countWriter counts the bytes written to the response
+type countWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (cw countWriter) Write(b []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(b)
+	cw.n.Add(int64(n))
+	return n, err
+}
+
+func (p *Proxy) serveFile(w http.ResponseWriter, r *http.Request, path, cacheControl string) {
+	f, err := os.Open(path)
+	if err != nil {
+		p.serveError(w, r, err)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		p.serveError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(countWriter{w, &p.bytes}, r, filepath.Base(path), fi.ModTime(), f)
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/internal/repotest"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+type upstream struct {
+	*repotest.Repo
+	gets atomic.Int64
+}
+
+// newUpstream serves a signed x86_64 repository with the packages over http
+func newUpstream(t *testing.T, pkgs map[string]string) *upstream {
+	return newUpstreamArch(t, "x86_64", pkgs)
+}
+
+// newUpstreamArch serves a signed x86_64 repository with packages of arch
+func newUpstreamArch(t *testing.T, arch string, pkgs map[string]string) *upstream {
+	t.Helper()
+	up := &upstream{Repo: repotest.New(t, "x86_64")}
+	for name, content := range pkgs {
+		up.Add(t, name+"-1.0_1", arch, content)
+	}
+	path := up.WriteRepodata(t)
+	// use a modification time with second precision like Last-Modified
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	up.Hook = func(r *http.Request) {
+		if r.Method == http.MethodGet {
+			up.gets.Add(1)
+		}
+	}
+	return up
+}
+
+func get(t *testing.T, srv *httptest.Server, path string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, buf
+}
+
+func TestProxy(t *testing.T) {
+	up := newUpstream(t, map[string]string{"foo": "foo package"})
+	dir := t.TempDir()
+	p, err := New(up.URL, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	if code, _ := get(t, srv, "/x86_64-repodata"); code != http.StatusOK {
+		t.Fatalf("repodata: unexpected status %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, p.Upstream.CacheString(), "x86_64-repodata")); err != nil {
+		t.Fatalf("repodata is not cached: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		if code, buf := get(t, srv, "/foo-1.0_1.x86_64.xbps"); code != http.StatusOK || string(buf) != "foo package" {
+			t.Fatalf("package: unexpected response %d %q", code, buf)
+		}
+	}
+	if code, _ := get(t, srv, "/foo-1.0_1.x86_64.xbps.sig2"); code != http.StatusOK {
+		t.Fatalf("signature: unexpected status %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, p.Upstream.CacheString(), "foo-1.0_1.x86_64.xbps")); err != nil {
+		t.Fatalf("package is not cached: %s", err)
+	}
+	// repodata, package and signature are fetched once
+	if n := up.gets.Load(); n != 3 {
+		t.Fatalf("expected 3 upstream requests, got %d", n)
+	}
+	for _, path := range []string{"/foo-0.9_1.x86_64.xbps", "/bar-1.0_1.x86_64.xbps", "/.hidden", "/foo"} {
+		if code, _ := get(t, srv, path); code != http.StatusNotFound {
+			t.Errorf("%s: expected not found, got %d", path, code)
+		}
+	}
+
+	code, buf := get(t, srv, StatsPath)
+	if code != http.StatusOK {
+		t.Fatalf("stats: unexpected status %d", code)
+	}
+	var stats Stats
+	if err := json.Unmarshal(buf, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Hits != 2 || stats.Misses != 2 || stats.BytesServed == 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestProxyRevalidate(t *testing.T) {
+	up := newUpstream(t, map[string]string{"foo": "foo package"})
+	p, err := New(up.URL, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.TTL = time.Nanosecond
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		if code, _ := get(t, srv, "/x86_64-repodata"); code != http.StatusOK {
+			t.Fatalf("repodata: unexpected status %d", code)
+		}
+	}
+	if n := up.gets.Load(); n != 1 {
+		t.Fatalf("unchanged repodata was downloaded %d times", n)
+	}
+	if stats := p.Stats(); stats.Revalidations != 3 || stats.Hits != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// a tampered package does not match the index and is not served
+	if err := os.WriteFile(filepath.Join(up.Dir, "foo-1.0_1.x86_64.xbps"), []byte("evil package"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var errlog bytes.Buffer
+	p.ErrorLog = log.New(&errlog, "", 0)
+	code, buf := get(t, srv, "/foo-1.0_1.x86_64.xbps")
+	if code != http.StatusBadGateway {
+		t.Fatalf("expected bad gateway for tampered package, got %d", code)
+	}
+	if strings.Contains(string(buf), "hash") || !strings.Contains(errlog.String(), "hash mismatch") {
+		t.Fatalf("upstream error is not logged or sent to the client: %q, log %q", buf, errlog.String())
+	}
+
+	// upstream failures keep serving the cached repository data
+	up.Close()
+	if code, _ := get(t, srv, "/x86_64-repodata"); code != http.StatusOK {
+		t.Fatalf("repodata: unexpected status %d with upstream down", code)
+	}
+}
+
+func TestProxyNoarch(t *testing.T) {
+	up := newUpstreamArch(t, "noarch", map[string]string{"foo": "foo package"})
+	p, err := New(up.URL, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Archs = []string{"aarch64", "x86_64"}
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	// the repository data was not requested before
+	if code, buf := get(t, srv, "/foo-1.0_1.noarch.xbps"); code != http.StatusOK || string(buf) != "foo package" {
+		t.Fatalf("noarch package: unexpected response %d %q", code, buf)
+	}
+}
+
+func TestProxyRepodataKey(t *testing.T) {
+	up := newUpstream(t, map[string]string{"foo": "foo package"})
+	priv, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	up.SignRepodata(t, priv)
+	p, err := New(up.URL, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	// the signature is not served without a key to verify it
+	if code, _ := get(t, srv, "/x86_64-repodata"+minisign.Ext); code != http.StatusNotFound {
+		t.Fatalf("signature without key: unexpected status %d", code)
+	}
+
+	p.RepodataKey = &repo.PublicKey{Minisign: priv.Public()}
+	code, sig := get(t, srv, "/x86_64-repodata"+minisign.Ext)
+	if code != http.StatusOK {
+		t.Fatalf("signature: unexpected status %d", code)
+	}
+	_, data := get(t, srv, "/x86_64-repodata")
+	if err := minisign.Verify(priv.Public(), bytes.NewReader(data), sig); err != nil {
+		t.Fatalf("served signature does not verify: %v", err)
+	}
+
+	// repository data signed by another key is not served
+	other, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	up.SignRepodata(t, other)
+	p2, err := New(up.URL, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2.RepodataKey = p.RepodataKey
+	srv2 := httptest.NewServer(p2)
+	defer srv2.Close()
+	for _, name := range []string{"/x86_64-repodata", "/x86_64-repodata" + minisign.Ext} {
+		if code, _ := get(t, srv2, name); code == http.StatusOK {
+			t.Fatalf("%s: unexpected status %d for repodata of another key", name, code)
+		}
+	}
+}
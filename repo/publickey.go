@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"bytes"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -10,6 +11,8 @@ import (
 
 	"howett.net/plist"
 	"golang.org/x/crypto/ssh"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
 )
 
 // plist structure of xbps generated key files.
@@ -19,8 +22,11 @@ type pubKey struct {
 	SignedBy string `plist:"signature-by"`
 }
 
+// PublicKey is a repository signing key, either an xbps RSA key or an
+// Ed25519 key in the minisign format.
 type PublicKey struct {
 	Key      *rsa.PublicKey
+	Minisign *minisign.PublicKey
 	Size     uint16
 	SignedBy string
 }
@@ -31,7 +37,44 @@ func (p *PublicKey) UnmarshalPlist(unmarshal func(interface{}) error) error {
 		return err
 	}
 	p.Size, p.SignedBy = data.Size, data.SignedBy
-	block, _ := pem.Decode(data.Key)
+	return p.parseKey(data.Key)
+}
+
+func (p *PublicKey) MarshalPlist() (interface{}, error) {
+	if p.Minisign != nil {
+		key, err := p.Minisign.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return &pubKey{Key: key, Size: p.Size, SignedBy: p.SignedBy}, nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(p.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &pubKey{
+		Key:      pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		Size:     p.Size,
+		SignedBy: p.SignedBy,
+	}, nil
+}
+
+// parseKey parses a PEM encoded RSA or a minisign public key
+func (p *PublicKey) parseKey(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		return p.parsePEM(data)
+	}
+	key, err := minisign.ParsePublicKey(data)
+	if err != nil {
+		return err
+	}
+	p.Minisign = key
+	return nil
+}
+
+// parsePEM parses the PEM encoded public key
+func (p *PublicKey) parsePEM(data []byte) error {
+	block, _ := pem.Decode(data)
 	if block == nil {
 		return errors.New("failed to decode PEM block")
 	}
@@ -49,7 +92,15 @@ func (p *PublicKey) UnmarshalPlist(unmarshal func(interface{}) error) error {
 	default:
 		return errors.New("unsupported public key type")
 	}
-	return nil
+}
+
+// PublicKey returns the parsed public key of the repository
+func (m *Meta) PublicKey() (*PublicKey, error) {
+	p := &PublicKey{Size: m.Size, SignedBy: m.SignedBy}
+	if err := p.parseKey(m.Key); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 // Returns the path where xbps would store the public key
@@ -65,12 +116,49 @@ func (p *PublicKey) Filename() string {
 // Returns an OpenSSH compatible public key fingerprint
 func (p *PublicKey) Fingerprint() string {
 	// BUG(duncaen): xbps uses md5 fingerprints, this is the old format openssh used
-	pubKey, err := ssh.NewPublicKey(p.Key)
+	return ssh.FingerprintLegacyMD5(p.sshKey())
+}
+
+// Returns the OpenSSH SHA256 fingerprint, like SHA256:<base64>
+func (p *PublicKey) FingerprintSHA256() string {
+	return ssh.FingerprintSHA256(p.sshKey())
+}
+
+// Returns true if fingerprint is the legacy or SHA256 fingerprint of the key
+func (p *PublicKey) MatchFingerprint(fingerprint string) bool {
+	return fingerprint == p.Fingerprint() || fingerprint == p.FingerprintSHA256()
+}
+
+// Returns true if both keys are the same RSA or Ed25519 key
+func (p *PublicKey) Equal(o *PublicKey) bool {
+	switch {
+	case p.Minisign != nil && o.Minisign != nil:
+		return p.Minisign.ID == o.Minisign.ID && p.Minisign.Key.Equal(o.Minisign.Key)
+	case p.Key != nil && o.Key != nil:
+		return p.Key.Equal(o.Key)
+	}
+	return false
+}
+
+// Returns the extension of detached signatures made with the key
+func (p *PublicKey) SignatureExt() string {
+	if p.Minisign != nil {
+		return minisign.Ext
+	}
+	return ".sig2"
+}
+
+func (p *PublicKey) sshKey() ssh.PublicKey {
+	var key interface{} = p.Key
+	if p.Minisign != nil {
+		key = p.Minisign.Key
+	}
+	pubKey, err := ssh.NewPublicKey(key)
 	if err != nil {
-		// this should never happen with rsa keys
+		// this should never happen with rsa and ed25519 keys
 		panic(err)
 	}
-	return ssh.FingerprintLegacyMD5(pubKey)
+	return pubKey
 }
 
 func ParsePublicKey(data []byte, key *PublicKey) error {
@@ -8,44 +8,56 @@
 package repo
 
 import (
-	"errors"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/Duncaen/go-xbps/crypto/minisign"
 	"github.com/Duncaen/go-xbps/repo/uri"
 )
 
+// Package is the metadata of a binary package, the plist and JSON
+// representations use the xbps property names as keys.
 type Package struct {
-	Alternatives    map[string][]string `plist:"alternatives"`
-	Architecture    string              `plist:"architecture"`
-	BuildDate       string              `plist:"build-date"`
-	BuildOptions    string              `plist:"build-options"`
-	ConfFiles       []string            `plist:"conf_files"`
-	Conflicts       []string            `plist:"conflicts"`
-	FilenameSHA256  string              `plist:"filename-sha256"`
-	FilenameSize    int64               `plist:"filename-size"`
-	Homepage        string              `plist:"homepage"`
-	InstalledSize   int64               `plist:"installed_size"`
-	License         string              `plist:"license"`
-	Maintainer      string              `plist:"maintainer"`
-	PkgVer          string              `plist:"pkgver"`
-	Preserve        bool                `plist:"preserve"`
-	Replaces        []string            `plist:"replaces"`
-	Reverts         []string            `plist:"reverts"`
-	RunDepends      []string            `plist:"run_depends"`
-	ShlibProvides   []string            `plist:"shlib-provides"`
-	ShlibRequires   []string            `plist:"shlib-requires"`
-	ShortDesc       string              `plist:"short_desc"`
-	SourceRevisions string              `plist:"source-revisions"`
-	SourcePkg       string              `plist:"sourcepkg"`
-}
-
-// Meta is a legacy xbps RSA public key
+	Alternatives    map[string][]string `plist:"alternatives,omitempty" json:"alternatives,omitempty"`
+	Architecture    string              `plist:"architecture,omitempty" json:"architecture,omitempty"`
+	BuildDate       string              `plist:"build-date,omitempty" json:"build-date,omitempty"`
+	BuildOptions    string              `plist:"build-options,omitempty" json:"build-options,omitempty"`
+	ConfFiles       []string            `plist:"conf_files,omitempty" json:"conf_files,omitempty"`
+	Conflicts       []string            `plist:"conflicts,omitempty" json:"conflicts,omitempty"`
+	FilenameSHA256  string              `plist:"filename-sha256,omitempty" json:"filename-sha256,omitempty"`
+	FilenameSize    int64               `plist:"filename-size,omitempty" json:"filename-size,omitempty"`
+	Homepage        string              `plist:"homepage,omitempty" json:"homepage,omitempty"`
+	InstalledSize   int64               `plist:"installed_size,omitempty" json:"installed_size,omitempty"`
+	License         string              `plist:"license,omitempty" json:"license,omitempty"`
+	Maintainer      string              `plist:"maintainer,omitempty" json:"maintainer,omitempty"`
+	PkgVer          string              `plist:"pkgver,omitempty" json:"pkgver,omitempty"`
+	Preserve        bool                `plist:"preserve,omitempty" json:"preserve,omitempty"`
+	Provides        []string            `plist:"provides,omitempty" json:"provides,omitempty"`
+	Replaces        []string            `plist:"replaces,omitempty" json:"replaces,omitempty"`
+	Reverts         []string            `plist:"reverts,omitempty" json:"reverts,omitempty"`
+	RunDepends      []string            `plist:"run_depends,omitempty" json:"run_depends,omitempty"`
+	ShlibProvides   []string            `plist:"shlib-provides,omitempty" json:"shlib-provides,omitempty"`
+	ShlibRequires   []string            `plist:"shlib-requires,omitempty" json:"shlib-requires,omitempty"`
+	ShortDesc       string              `plist:"short_desc,omitempty" json:"short_desc,omitempty"`
+	SourceRevisions string              `plist:"source-revisions,omitempty" json:"source-revisions,omitempty"`
+	SourcePkg       string              `plist:"sourcepkg,omitempty" json:"sourcepkg,omitempty"`
+}
+
+// Filename returns the file name of the binary package
+func (p *Package) Filename() string {
+	return fmt.Sprintf("%s.%s.xbps", p.PkgVer, p.Architecture)
+}
+
+// Meta is the repository public key, a legacy xbps RSA or a minisign Ed25519 key
 type Meta struct {
-	Key      []byte `plist:"public-key"`
-	Size     uint16 `plist:"public-key-size"`
-	SignedBy string `plist:"signature-by"`
+	Key      []byte `plist:"public-key" json:"public-key"`
+	Size     uint16 `plist:"public-key-size" json:"public-key-size"`
+	SignedBy string `plist:"signature-by" json:"signature-by"`
 }
 
 // Repository is the parsed repository file
@@ -54,7 +66,20 @@ type Repository struct {
 	Arch string
 	// URI is the parsed repository URI
 	URI *uri.URI
-	// Meta is the repositories legacy xbps RSA public key
+	// Mirrors are the mirrors used to fetch remote repository files,
+	// if nil files are fetched from URI.
+	Mirrors *uri.Mirrors
+	// CacheDir is the cache directory for remote repository data
+	CacheDir string
+	// IndexCache enables reading the repository data from a binary index
+	// cache, which is created next to the cached repository data by Open.
+	IndexCache bool
+	// RepodataKey is the trusted Ed25519 key of the repository data, if set
+	// Sync and Open require a valid detached minisign signature of the
+	// repository data. Sync stores the signature next to the cached
+	// repository data, which Open verifies again.
+	RepodataKey *PublicKey
+	// Meta is the repositories public key
 	Meta *Meta
 	// Index is the repository index, mapping package names to packages
 	Index map[string]Package
@@ -71,8 +96,128 @@ func New(url, arch string) (*Repository, error) {
 	return &Repository{URI: uri, Arch: arch}, nil
 }
 
-func (r *Repository) Sync() error {
-	return errors.New("not implemented")
+// Fetch fetches the file name from the repository or its mirrors
+func (r *Repository) Fetch(ctx context.Context, name string, offset int64) (*uri.File, error) {
+	if r.Mirrors != nil {
+		return r.Mirrors.Fetch(ctx, name, offset)
+	}
+	return r.URI.Fetch(ctx, name, offset)
+}
+
+// Sync downloads the remote repository data into the cache directory.
+//
+// Repository data is only accepted if it decodes and is not older than
+// the cached repository data, otherwise Sync fails over to the next mirror.
+// Local repositories do not need to be synced.
+func (r *Repository) Sync(ctx context.Context) error {
+	if !r.URI.IsRemote() {
+		return nil
+	}
+	repodata, err := r.URI.Repodata(r.Arch, r.CacheDir)
+	if err != nil {
+		return fmt.Errorf("repo could not be synced: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(repodata), 0o755); err != nil {
+		return fmt.Errorf("repo could not be synced: %w", err)
+	}
+	var cached time.Time
+	if fi, err := os.Stat(repodata); err == nil {
+		cached = fi.ModTime()
+	}
+	mirrors := r.Mirrors
+	if mirrors == nil {
+		mirrors = uri.NewMirrors(r.URI)
+	}
+	err = mirrors.Do(ctx, func(u *uri.URI) error {
+		return syncRepodata(ctx, u, repodataName(r.Arch), repodata, cached, r.RepodataKey)
+	})
+	if err != nil {
+		return fmt.Errorf("repo could not be synced: %w", err)
+	}
+	return nil
+}
+
+// syncRepodata downloads the repository data from u and atomically replaces path
+func syncRepodata(ctx context.Context, u *uri.URI, name, path string, cached time.Time, key *PublicKey) error {
+	f, err := u.Fetch(ctx, name, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if !f.ModTime.IsZero() && f.ModTime.Before(cached) {
+		return fmt.Errorf("repodata from %s is older than cached repodata from %s: %w",
+			f.ModTime.UTC(), cached.UTC(), uri.ErrStale)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".repodata-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, f); err != nil {
+		return err
+	}
+	var sig []byte
+	if key != nil {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if sig, err = fetchRepodataSig(ctx, u, name); err != nil {
+			return err
+		}
+		if err := verifyRepodata(key, tmp, sig); err != nil {
+			return err
+		}
+	}
+	// refuse repository data that does not decode
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := (&Repository{}).ReadFrom(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !f.ModTime.IsZero() {
+		if err := os.Chtimes(tmp.Name(), f.ModTime, f.ModTime); err != nil {
+			return err
+		}
+	}
+	if sig != nil {
+		// Open verifies the cached repository data with the signature
+		if err := WriteFile(path+minisign.Ext, bytes.NewReader(sig), time.Time{}); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteFile atomically replaces the file at path with the data read from r,
+// the modification time is set to modTime unless it is zero
+func WriteFile(path string, r io.Reader, modTime time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !modTime.IsZero() {
+		if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
 // Open opens and reads a new repository
@@ -89,22 +234,128 @@ func Open(url, arch string) (*Repository, error) {
 }
 
 // Open reads the repository data from the repositories uri
+//
+// Remote repository data is read from the cache directory, local repository
+// data is fetched through the fetcher of the URIs scheme. With RepodataKey
+// the repository data is verified with the signature stored by Sync or the
+// signature next to the local repository data.
 func (repo *Repository) Open() error {
-	repodata, err := repo.URI.Repodata(repo.Arch, "")
+	var rd io.ReadCloser
+	var sigpath string
+	if repo.URI.IsRemote() {
+		repodata, err := repo.URI.Repodata(repo.Arch, repo.CacheDir)
+		if err != nil {
+			return fmt.Errorf("repo could no be opened: %w", err)
+		}
+		f, err := os.Open(repodata)
+		if err != nil {
+			return fmt.Errorf("repo could not be opened: %w", err)
+		}
+		rd = f
+		sigpath = repodata + minisign.Ext
+	} else {
+		f, err := repo.URI.Fetch(context.Background(), repodataName(repo.Arch), 0)
+		if err != nil {
+			return fmt.Errorf("repo could not be opened: %w", err)
+		}
+		rd = f
+	}
+	defer rd.Close()
+	sig := func() ([]byte, error) {
+		if sigpath == "" {
+			return fetchRepodataSig(context.Background(), repo.URI, repodataName(repo.Arch))
+		}
+		sig, err := os.ReadFile(sigpath)
+		if err != nil {
+			return nil, fmt.Errorf("repodata signature: %w", err)
+		}
+		return sig, nil
+	}
+	if err := repo.read(rd, sig); err != nil {
+		return fmt.Errorf("repo could not be read: %w", err)
+	}
+	return nil
+}
+
+// read reads the repository data, with RepodataKey the data is verified
+// with the signature returned by sig first.
+func (repo *Repository) read(rd io.Reader, sig func() ([]byte, error)) error {
+	if repo.RepodataKey != nil {
+		buf, err := io.ReadAll(rd)
+		if err != nil {
+			return err
+		}
+		s, err := sig()
+		if err != nil {
+			return err
+		}
+		if err := verifyRepodata(repo.RepodataKey, bytes.NewReader(buf), s); err != nil {
+			return err
+		}
+		rd = bytes.NewReader(buf)
+	}
+	if repo.IndexCache {
+		return repo.readCached(rd)
+	}
+	_, err := repo.ReadFrom(rd)
+	return err
+}
+
+// FetchRepodataSig fetches the detached minisign signature of the
+// repository data from the repository or its mirrors.
+func (r *Repository) FetchRepodataSig(ctx context.Context) ([]byte, error) {
+	f, err := r.Fetch(ctx, repodataName(r.Arch)+minisign.Ext, 0)
 	if err != nil {
-		return fmt.Errorf("repo could no be opened: %w", err)
+		return nil, fmt.Errorf("repodata signature: %w", err)
 	}
-	f, err := os.Open(repodata)
+	return readRepodataSig(f)
+}
+
+// VerifyRepodata verifies the repository data with its detached minisign
+// signature and RepodataKey.
+func (r *Repository) VerifyRepodata(data io.Reader, sig []byte) error {
+	if r.RepodataKey == nil {
+		return fmt.Errorf("repodata signature verification failed: no repodata key")
+	}
+	return verifyRepodata(r.RepodataKey, data, sig)
+}
+
+// fetchRepodataSig fetches the detached minisign signature of the
+// repository data from u.
+func fetchRepodataSig(ctx context.Context, u *uri.URI, name string) ([]byte, error) {
+	f, err := u.Fetch(ctx, name+minisign.Ext, 0)
 	if err != nil {
-		return fmt.Errorf("repo could not be opened: %w", err)
+		return nil, fmt.Errorf("repodata signature: %w", err)
 	}
+	return readRepodataSig(f)
+}
+
+// readRepodataSig reads and closes the fetched signature
+func readRepodataSig(f *uri.File) ([]byte, error) {
 	defer f.Close()
-	if _, err := repo.ReadFrom(f); err != nil {
-		return fmt.Errorf("repo could not be read: %w", err)
+	sig, err := io.ReadAll(io.LimitReader(f, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("repodata signature: %w", err)
+	}
+	return sig, nil
+}
+
+// verifyRepodata verifies the repository data with its detached minisign signature
+func verifyRepodata(key *PublicKey, data io.Reader, sig []byte) error {
+	if key.Minisign == nil {
+		return fmt.Errorf("repodata key %s is not an Ed25519 key", key.FingerprintSHA256())
+	}
+	if err := minisign.Verify(key.Minisign, data, sig); err != nil {
+		return fmt.Errorf("repodata signature verification failed: %w", err)
 	}
 	return nil
 }
 
+// repodataName returns the file name of the repository data for arch
+func repodataName(arch string) string {
+	return fmt.Sprintf("%s-repodata", arch)
+}
+
 // ReadFrom reads the repository data from the reader
 func (repo *Repository) ReadFrom(rd io.Reader) (int64, error) {
 	dec, err := NewDecoder(rd)
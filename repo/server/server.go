@@ -0,0 +1,326 @@
+// Package server implements serving a directory as xbps repository over HTTP.
+//
+// Only repository files are served: binary packages, their signatures and
+// repository data. Repository data is only served if it decodes, optionally
+// it is regenerated when binary packages in the directory change.
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/version"
+)
+
+const (
+	// packageCacheControl is used for packages and signatures, their names contain the version
+	packageCacheControl = "public, max-age=86400"
+	// repodataCacheControl forces clients to revalidate repository data
+	repodataCacheControl = "no-cache"
+)
+
+// Server serves a repository directory
+type Server struct {
+	// Dir is the repository directory
+	Dir string
+	// Regenerate enables regenerating the repository data if binary
+	// packages in the directory changed. Regenerated repository data is
+	// not signed, the signature of the replaced repository data is removed.
+	Regenerate bool
+
+	mu        sync.Mutex
+	checked   map[string]checked
+	indexed   map[string]indexed
+	generated map[string][sha256.Size]byte
+}
+
+// checked is the cached result of decoding repository data
+type checked struct {
+	size    int64
+	modTime time.Time
+	err     error
+}
+
+// indexed is a cached index entry of a binary package
+type indexed struct {
+	size    int64
+	modTime time.Time
+	pkg     *repo.Package
+}
+
+// New returns a server for the repository directory
+func New(dir string) *Server {
+	return &Server{Dir: dir}
+}
+
+// kind returns the kind of repository file or an empty string
+func kind(name string) string {
+	switch {
+	case strings.HasSuffix(name, "-repodata"):
+		return "repodata"
+	case strings.HasSuffix(name, "-repodata"+minisign.Ext):
+		return "signature"
+	case strings.HasSuffix(name, ".xbps"),
+		strings.HasSuffix(name, ".xbps.sig"),
+		strings.HasSuffix(name, ".xbps.sig2"),
+		strings.HasSuffix(name, ".xbps.minisig"):
+		return "package"
+	}
+	return ""
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p := path.Clean("/" + r.URL.Path)
+	if p == "/" {
+		s.serveListing(w, r)
+		return
+	}
+	name := p[1:]
+	if strings.Contains(name, "/") || name[0] == '.' {
+		http.NotFound(w, r)
+		return
+	}
+	k := kind(name)
+	if s.Regenerate && (k == "repodata" || k == "signature") {
+		arch := strings.TrimSuffix(strings.TrimSuffix(name, minisign.Ext), "-repodata")
+		if err := s.regenerate(arch); err != nil {
+			http.Error(w, fmt.Sprintf("failed to generate repository data: %s", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	switch k {
+	case "repodata":
+		s.serveFile(w, r, name, repodataCacheControl, s.check)
+	case "signature":
+		s.serveFile(w, r, name, repodataCacheControl, nil)
+	case "package":
+		s.serveFile(w, r, name, packageCacheControl, nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveFile serves the file with caching headers and range support
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, name, cacheControl string, check func(string, os.FileInfo) error) {
+	f, err := os.Open(filepath.Join(s.Dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "failed to open file", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	if check != nil {
+		if err := check(name, fi); err != nil {
+			http.Error(w, fmt.Sprintf("refusing to serve %s: %s", name, err), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, fi.Size(), fi.ModTime().UnixNano()))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, name, fi.ModTime(), f)
+}
+
+// check decodes the repository data and caches the result
+func (s *Server) check(name string, fi os.FileInfo) error {
+	s.mu.Lock()
+	c, ok := s.checked[name]
+	s.mu.Unlock()
+	if ok && c.size == fi.Size() && c.modTime.Equal(fi.ModTime()) {
+		return c.err
+	}
+	c = checked{size: fi.Size(), modTime: fi.ModTime()}
+	f, err := os.Open(filepath.Join(s.Dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := (&repo.Repository{}).ReadFrom(f); err != nil {
+		c.err = err
+	}
+	s.mu.Lock()
+	if s.checked == nil {
+		s.checked = make(map[string]checked)
+	}
+	s.checked[name] = c
+	s.mu.Unlock()
+	return c.err
+}
+
+// serveListing serves a directory listing of the repository files
+func (s *Server) serveListing(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		http.Error(w, "failed to read directory", http.StatusInternalServerError)
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && kind(e.Name()) != "" && e.Name()[0] != '.' {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", repodataCacheControl)
+	if r.Method == http.MethodHead {
+		return
+	}
+	fmt.Fprintln(w, "<!DOCTYPE html>\n<html><head><title>Index of /</title></head><body><h1>Index of /</h1><pre>")
+	for _, name := range names {
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(name), html.EscapeString(name))
+	}
+	fmt.Fprintln(w, "</pre></body></html>")
+}
+
+// regenerate writes new repository data for arch if binary packages changed
+func (s *Server) regenerate(arch string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+	repodata := filepath.Join(s.Dir, fmt.Sprintf("%s-repodata", arch))
+	_, staterr := os.Stat(repodata)
+
+	// skip regeneration if the package files did not change since the last run
+	h := sha256.New()
+	var files []os.FileInfo
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasSuffix(name, ".xbps") || name[0] == '.' {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, fi)
+		fmt.Fprintf(h, "%s %d %d\n", name, fi.Size(), fi.ModTime().UnixNano())
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	if s.generated == nil {
+		s.generated = make(map[string][sha256.Size]byte)
+	}
+	if prev, ok := s.generated[arch]; ok && prev == sum && staterr == nil {
+		return nil
+	}
+
+	index := s.index(arch, files)
+	r := &repo.Repository{Index: index}
+	if f, err := os.Open(repodata); err == nil {
+		old := &repo.Repository{}
+		_, err := old.ReadFrom(f)
+		f.Close()
+		if err == nil {
+			// keep the public key of the existing repository data
+			r.Meta = old.Meta
+			if sameIndex(old.Index, index) {
+				s.generated[arch] = sum
+				return nil
+			}
+		}
+	}
+	// the signature of the replaced repository data is stale
+	if err := os.Remove(repodata + minisign.Ext); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := writeRepodata(repodata, r); err != nil {
+		return err
+	}
+	s.generated[arch] = sum
+	return nil
+}
+
+// index returns the index of the newest packages for arch
+func (s *Server) index(arch string, files []os.FileInfo) map[string]repo.Package {
+	if s.indexed == nil {
+		s.indexed = make(map[string]indexed)
+	}
+	seen := make(map[string]bool)
+	index := make(map[string]repo.Package)
+	for _, fi := range files {
+		name := fi.Name()
+		seen[name] = true
+		idx, ok := s.indexed[name]
+		if !ok || idx.size != fi.Size() || !idx.modTime.Equal(fi.ModTime()) {
+			pkg, err := binpkg.IndexPackage(filepath.Join(s.Dir, name))
+			if err != nil {
+				// skip packages that are still being written
+				continue
+			}
+			idx = indexed{fi.Size(), fi.ModTime(), pkg}
+			s.indexed[name] = idx
+		}
+		if idx.pkg.Architecture != arch && idx.pkg.Architecture != "noarch" {
+			continue
+		}
+		pv, _ := pkgver.Parse(idx.pkg.PkgVer)
+		if prev, ok := index[pv.Name]; ok {
+			prevpv, _ := pkgver.Parse(prev.PkgVer)
+			if version.Cmp(prevpv.Version, pv.Version) >= 0 {
+				continue
+			}
+		}
+		index[pv.Name] = *idx.pkg
+	}
+	for name := range s.indexed {
+		if !seen[name] {
+			delete(s.indexed, name)
+		}
+	}
+	return index
+}
+
+// sameIndex returns true if both indexes contain the same package files
+func sameIndex(a, b map[string]repo.Package) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, pkg := range a {
+		if other, ok := b[name]; !ok || other.PkgVer != pkg.PkgVer || other.FilenameSHA256 != pkg.FilenameSHA256 {
+			return false
+		}
+	}
+	return true
+}
+
+// writeRepodata atomically replaces the repository data at path
+func writeRepodata(path string, r *repo.Repository) error {
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		return err
+	}
+	return repo.WriteFile(path, &buf, time.Time{})
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func writePackage(t *testing.T, dir, pkgver, arch string) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(dir, pkgver+"."+arch+".xbps"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := binpkg.NewWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := &binpkg.Metadata{Props: repo.Package{PkgVer: pkgver, Architecture: arch, ShortDesc: "test package"}}
+	if err := w.WriteMetadata(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(t *testing.T, srv *httptest.Server, path string, header map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestServePackage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "foo-1.0_1.noarch.xbps"), []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(dir))
+	defer srv.Close()
+
+	resp := get(t, srv, "/foo-1.0_1.noarch.xbps", map[string]string{"Range": "bytes=4-"})
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected partial content, got %s", resp.Status)
+	}
+	if buf, _ := io.ReadAll(resp.Body); string(buf) != "456789" {
+		t.Fatalf("unexpected range content %q", buf)
+	}
+	if resp.Header.Get("Cache-Control") != packageCacheControl {
+		t.Fatalf("unexpected Cache-Control %q", resp.Header.Get("Cache-Control"))
+	}
+	etag := resp.Header.Get("ETag")
+	resp = get(t, srv, "/foo-1.0_1.noarch.xbps", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected not modified, got %s", resp.Status)
+	}
+	for _, path := range []string{"/secret.txt", "/.hidden.xbps", "/bar-1.0_1.noarch.xbps"} {
+		if resp := get(t, srv, path, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected not found, got %s", path, resp.Status)
+		}
+	}
+
+	resp = get(t, srv, "/", nil)
+	buf, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(buf), "foo-1.0_1.noarch.xbps") || strings.Contains(string(buf), "secret.txt") {
+		t.Fatalf("unexpected listing: %s", buf)
+	}
+}
+
+func TestServeCorruptRepodata(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x86_64-repodata"), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(dir))
+	defer srv.Close()
+	if resp := get(t, srv, "/x86_64-repodata", nil); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected service unavailable, got %s", resp.Status)
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	dir := t.TempDir()
+	writePackage(t, dir, "foo-1.0_1", "x86_64")
+	writePackage(t, dir, "bar-1.0_1", "noarch")
+	writePackage(t, dir, "baz-1.0_1", "aarch64")
+	s := New(dir)
+	s.Regenerate = true
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	open := func() *repo.Repository {
+		t.Helper()
+		r, err := repo.New(srv.URL, "x86_64")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.CacheDir = t.TempDir()
+		if err := r.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	r := open()
+	if len(r.Index) != 2 || r.Index["foo"].PkgVer != "foo-1.0_1" || r.Index["bar"].PkgVer != "bar-1.0_1" {
+		t.Fatalf("unexpected index %v", r.Index)
+	}
+	if r.Index["foo"].FilenameSHA256 == "" || r.Index["foo"].FilenameSize == 0 {
+		t.Fatalf("package hash and size are not indexed: %+v", r.Index["foo"])
+	}
+
+	writePackage(t, dir, "foo-1.1_1", "x86_64")
+	if err := os.Remove(filepath.Join(dir, "bar-1.0_1.noarch.xbps")); err != nil {
+		t.Fatal(err)
+	}
+	r = open()
+	if len(r.Index) != 1 || r.Index["foo"].PkgVer != "foo-1.1_1" {
+		t.Fatalf("unexpected index after update %v", r.Index)
+	}
+}
+
+func TestRegenerateRemovesSignature(t *testing.T) {
+	dir := t.TempDir()
+	writePackage(t, dir, "foo-1.0_1", "x86_64")
+	s := New(dir)
+	s.Regenerate = true
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	if resp := get(t, srv, "/x86_64-repodata", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", resp.Status)
+	}
+	sig := filepath.Join(dir, "x86_64-repodata"+minisign.Ext)
+	if err := os.WriteFile(sig, []byte("signature"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if resp := get(t, srv, "/x86_64-repodata"+minisign.Ext, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("signature of unchanged repodata: unexpected status %s", resp.Status)
+	}
+
+	// the signature does not match the regenerated repository data
+	writePackage(t, dir, "foo-1.1_1", "x86_64")
+	if resp := get(t, srv, "/x86_64-repodata"+minisign.Ext, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("stale signature: unexpected status %s", resp.Status)
+	}
+	if _, err := os.Stat(sig); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected stale signature to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/crypto/minisign"
+	"github.com/Duncaen/go-xbps/repo/uri"
+)
+
+// writeRepodata writes repository data with the index to path
+func writeRepodata(t *testing.T, path string, index map[string]Package) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := (&Repository{Index: index}).WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	writeRepodata(t, dir+"/x86_64-repodata", map[string]Package{
+		"foo": {PkgVer: "foo-1.0_1", Architecture: "x86_64"},
+	})
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+	good := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer good.Close()
+
+	r, err := New(good.URL, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := uri.Parse(broken.URL)
+	r.Mirrors = uri.NewMirrors(b, r.URI)
+	r.CacheDir = t.TempDir()
+	if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Index["foo"].PkgVer != "foo-1.0_1" {
+		t.Fatalf("unexpected index: %v", r.Index)
+	}
+	if st := r.Mirrors.Status(); st[0].Failures != 1 {
+		t.Fatalf("expected broken mirror to fail: %+v", st)
+	}
+
+	// pretend the cached repodata is newer than the mirrors
+	repodata, _ := r.URI.Repodata(r.Arch, r.CacheDir)
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(repodata, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sync(context.Background()); !errors.Is(err, uri.ErrStale) {
+		t.Fatalf("expected ErrStale, got %v", err)
+	}
+}
+
+func TestSyncCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/x86_64-repodata", []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	r, err := New(srv.URL, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CacheDir = t.TempDir()
+	if err := r.Sync(context.Background()); err == nil {
+		t.Fatal("expected corrupt repodata to be rejected")
+	}
+	repodata, _ := r.URI.Repodata(r.Arch, r.CacheDir)
+	if _, err := os.Stat(repodata); err == nil {
+		t.Fatal("corrupt repodata was written to the cache")
+	}
+}
+
+// dirFetcher is a fake transport that serves files from a local directory
+type dirFetcher struct {
+	dir string
+}
+
+func (d dirFetcher) local(u *uri.URI) *uri.URI {
+	return &uri.URI{Path: d.dir + "/" + u.Host + u.Path}
+}
+
+func (d dirFetcher) Fetch(ctx context.Context, u *uri.URI, name string, offset int64) (*uri.File, error) {
+	return uri.LocalFetcher{}.Fetch(ctx, d.local(u), name, offset)
+}
+
+func (d dirFetcher) Stat(ctx context.Context, u *uri.URI, name string) (fs.FileInfo, error) {
+	return uri.LocalFetcher{}.Stat(ctx, d.local(u), name)
+}
+
+func TestSyncFetcher(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(dir+"/host/current", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeRepodata(t, dir+"/host/current/x86_64-repodata", map[string]Package{
+		"foo": {PkgVer: "foo-1.0_1", Architecture: "x86_64"},
+	})
+	uri.Register("fake", true, dirFetcher{dir})
+	defer uri.Unregister("fake")
+
+	r, err := New("fake://host/current", "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CacheDir = t.TempDir()
+	if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Index["foo"].PkgVer != "foo-1.0_1" {
+		t.Fatalf("unexpected index: %v", r.Index)
+	}
+}
+
+func TestSyncRepodataKey(t *testing.T) {
+	dir := t.TempDir()
+	writeRepodata(t, dir+"/x86_64-repodata", map[string]Package{
+		"foo": {PkgVer: "foo-1.0_1", Architecture: "x86_64"},
+	})
+	priv, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(priv *minisign.PrivateKey) {
+		t.Helper()
+		f, err := os.Open(dir + "/x86_64-repodata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		sig, err := minisign.Sign(priv, f, "file:x86_64-repodata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := sig.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dir+"/x86_64-repodata"+minisign.Ext, buf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	r, err := New(srv.URL, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CacheDir = t.TempDir()
+	r.RepodataKey = &PublicKey{Minisign: priv.Public()}
+	if err := r.Sync(context.Background()); err == nil {
+		t.Fatal("expected error for unsigned repodata")
+	}
+	sign(priv)
+	if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	}
+	// the cached repository data is verified with the cached signature
+	cached, err := r.URI.Repodata(r.Arch, r.CacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRepodata(t, cached, map[string]Package{
+		"foo": {PkgVer: "foo-1.0_2", Architecture: "x86_64"},
+	})
+	if err := os.Chtimes(cached, time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Open(); !errors.Is(err, minisign.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if err := os.Remove(cached + minisign.Ext); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Open(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+
+	local, err := New(dir, "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local.RepodataKey = r.RepodataKey
+	if err := local.Open(); err != nil {
+		t.Fatal(err)
+	}
+	other, err := minisign.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign(other)
+	if err := local.Open(); !errors.Is(err, minisign.ErrKeyMismatch) {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if err := r.Sync(context.Background()); !errors.Is(err, minisign.ErrKeyMismatch) {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+}
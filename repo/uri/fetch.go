@@ -0,0 +1,224 @@
+package uri
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is the http client used to fetch files from remote repositories.
+var Client = http.DefaultClient
+
+// File is a file fetched from a repository.
+type File struct {
+	io.ReadCloser
+	// Offset is the offset in the file of the first byte read from the File.
+	Offset int64
+	// Size is the size of the whole file or -1 if it is unknown.
+	Size int64
+	// ModTime is the modification time of the file, zero if it is unknown.
+	ModTime time.Time
+}
+
+// Fetch opens the file name relative to the repository URI for reading,
+// starting at offset.
+//
+// Remote servers are free to ignore the offset, callers that resume downloads
+// have to check the Offset of the returned File.
+// If the file does not exist the returned error wraps fs.ErrNotExist.
+func (u *URI) Fetch(ctx context.Context, name string, offset int64) (*File, error) {
+	f, err := u.Fetcher()
+	if err != nil {
+		return nil, err
+	}
+	return f.Fetch(ctx, u, name, offset)
+}
+
+// Stat returns the file info of the file name relative to the repository URI.
+//
+// Remote file infos only provide the size and modification time if the server reports them.
+func (u *URI) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	f, err := u.Fetcher()
+	if err != nil {
+		return nil, err
+	}
+	return f.Stat(ctx, u, name)
+}
+
+// LocalFetcher fetches files from local repository directories.
+type LocalFetcher struct{}
+
+// Fetch implements Fetcher
+func (LocalFetcher) Fetch(ctx context.Context, u *URI, name string, offset int64) (*File, error) {
+	return fetchFile(filepath.Join(u.Path, name), offset)
+}
+
+// Stat implements Fetcher
+func (LocalFetcher) Stat(ctx context.Context, u *URI, name string) (fs.FileInfo, error) {
+	return os.Stat(filepath.Join(u.Path, name))
+}
+
+// HTTPFetcher fetches files from http and https repositories.
+type HTTPFetcher struct {
+	// Client is the http client, if nil the package level Client is used.
+	Client *http.Client
+}
+
+func (h *HTTPFetcher) client() *http.Client {
+	if h.Client != nil {
+		return h.Client
+	}
+	return Client
+}
+
+// Fetch implements Fetcher
+func (h *HTTPFetcher) Fetch(ctx context.Context, u *URI, name string, offset int64) (*File, error) {
+	return fetchHTTP(ctx, h.client(), (*URI)((*url.URL)(u).JoinPath(name)), offset)
+}
+
+// Stat implements Fetcher
+func (h *HTTPFetcher) Stat(ctx context.Context, u *URI, name string) (fs.FileInfo, error) {
+	return statHTTP(ctx, h.client(), (*URI)((*url.URL)(u).JoinPath(name)))
+}
+
+// fileInfo is the fs.FileInfo of remote files
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return 0o444 }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return false }
+func (fi *fileInfo) Sys() any           { return nil }
+
+func statHTTP(ctx context.Context, client *http.Client, u *URI) (fs.FileInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%s: %s: %w", u, resp.Status, fs.ErrNotExist)
+	default:
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	fi := &fileInfo{name: path.Base(u.Path), size: resp.ContentLength}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		fi.modTime = t
+	}
+	return fi, nil
+}
+
+func fetchFile(path string, offset int64) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if offset > st.Size() {
+		offset = st.Size()
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &File{ReadCloser: f, Offset: offset, Size: st.Size(), ModTime: st.ModTime()}, nil
+}
+
+func fetchHTTP(ctx context.Context, client *http.Client, u *URI, offset int64) (*File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{ReadCloser: resp.Body, Size: resp.ContentLength}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		f.ModTime = t
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return f, nil
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: invalid Content-Range: %q", u, resp.Header.Get("Content-Range"))
+		}
+		f.Offset, f.Size = start, size
+		return f, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the requested offset is the end of the file
+		_, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if ok && size == offset {
+			resp.Body.Close()
+			f.ReadCloser = io.NopCloser(strings.NewReader(""))
+			f.Offset, f.Size = offset, size
+			return f, nil
+		}
+	case http.StatusNotFound, http.StatusGone:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s: %w", u, resp.Status, fs.ErrNotExist)
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s: %s", u, resp.Status)
+}
+
+// parseContentRange parses the start and complete length of a Content-Range header.
+func parseContentRange(s string) (start, size int64, ok bool) {
+	s, ok = strings.CutPrefix(s, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, length, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	size = -1
+	if length != "*" {
+		var err error
+		if size, err = strconv.ParseInt(length, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rng == "*" {
+		return 0, size, true
+	}
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
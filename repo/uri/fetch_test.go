@@ -0,0 +1,46 @@
+package uri
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x86_64-repodata"), []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	for _, rawuri := range []string{dir, "file://" + dir, srv.URL} {
+		u, err := Parse(rawuri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, offset := range []int64{0, 4, 10} {
+			f, err := u.Fetch(context.Background(), "x86_64-repodata", offset)
+			if err != nil {
+				t.Fatalf("%s: %s", rawuri, err)
+			}
+			buf, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Offset != offset || f.Size != 10 || string(buf) != "0123456789"[offset:] {
+				t.Errorf("%s: offset %d: got offset %d size %d content %q", rawuri, offset, f.Offset, f.Size, buf)
+			}
+		}
+		if _, err := u.Fetch(context.Background(), "aarch64-repodata", 0); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: expected fs.ErrNotExist, got %v", rawuri, err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package uri
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBackoff is how long a failed mirror is skipped by default.
+const DefaultBackoff = time.Minute
+
+// ErrStale is returned by failover functions to reject a mirrors outdated data.
+var ErrStale = errors.New("mirror is stale")
+
+// MirrorStatus is the health of a mirror.
+type MirrorStatus struct {
+	URI *URI
+	// Failures is the number of consecutive failures
+	Failures int
+	// LastError is the error of the last failure
+	LastError error
+	// LastSuccess is the time of the last successful request
+	LastSuccess time.Time
+	// DownUntil is the time until the mirror is skipped
+	DownUntil time.Time
+}
+
+// Mirrors is an ordered set of mirrors of the same repository.
+//
+// Requests are tried on the mirrors in order and fail over to the next mirror
+// on errors. Failed mirrors are skipped for Backoff, doubled for each
+// consecutive failure, unless all mirrors are failing.
+type Mirrors struct {
+	// Backoff is the time a failed mirror is skipped, defaults to DefaultBackoff.
+	Backoff time.Duration
+
+	mu      sync.Mutex
+	mirrors []*MirrorStatus
+	now     func() time.Time
+}
+
+// NewMirrors returns the mirror set of the URIs in order of preference
+func NewMirrors(uris ...*URI) *Mirrors {
+	m := &Mirrors{now: time.Now}
+	for _, u := range uris {
+		m.mirrors = append(m.mirrors, &MirrorStatus{URI: u})
+	}
+	return m
+}
+
+// ParseMirrorList parses a mirror list with one repository URI per line.
+//
+// Empty lines and lines starting with # are ignored, lines may use the
+// repository=<uri> syntax of xbps.d configuration files.
+func ParseMirrorList(r io.Reader) (*Mirrors, error) {
+	var uris []*URI
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "repository=")
+		u, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("mirror list: line %d: %w", n, err)
+		}
+		uris = append(uris, u)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(uris) == 0 {
+		return nil, errors.New("mirror list: no mirrors")
+	}
+	return NewMirrors(uris...), nil
+}
+
+// URIs returns the mirror URIs in the order they are tried,
+// mirrors that are currently skipped come last.
+func (m *Mirrors) URIs() []*URI {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var up, down []*URI
+	now := m.clock()
+	for _, s := range m.mirrors {
+		if now.Before(s.DownUntil) {
+			down = append(down, s.URI)
+		} else {
+			up = append(up, s.URI)
+		}
+	}
+	return append(up, down...)
+}
+
+// Status returns the health of all mirrors in their configured order
+func (m *Mirrors) Status() []MirrorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]MirrorStatus, len(m.mirrors))
+	for i, s := range m.mirrors {
+		res[i] = *s
+	}
+	return res
+}
+
+// clock returns the current time, the zero Mirrors uses time.Now
+func (m *Mirrors) clock() time.Time {
+	if m.now == nil {
+		return time.Now()
+	}
+	return m.now()
+}
+
+func (m *Mirrors) status(u *URI) *MirrorStatus {
+	for _, s := range m.mirrors {
+		if s.URI == u {
+			return s
+		}
+	}
+	return nil
+}
+
+// Succeeded marks the mirror as healthy
+func (m *Mirrors) Succeeded(u *URI) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.status(u); s != nil {
+		s.Failures, s.LastError, s.DownUntil = 0, nil, time.Time{}
+		s.LastSuccess = m.clock()
+	}
+}
+
+// Failed records a failure of the mirror
+func (m *Mirrors) Failed(u *URI, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.status(u)
+	if s == nil {
+		return
+	}
+	backoff := m.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	s.Failures++
+	s.LastError = err
+	s.DownUntil = m.clock().Add(backoff << min(s.Failures-1, 10))
+}
+
+// Do calls fn with each mirror until fn succeeds.
+//
+// Errors are recorded as mirror failures, except for files that do not exist
+// on a mirror and the context being done, which stops the failover.
+// If all mirrors fail, the joined errors are returned.
+func (m *Mirrors) Do(ctx context.Context, fn func(u *URI) error) error {
+	var errs []error
+	for _, u := range m.URIs() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn(u)
+		if err == nil {
+			m.Succeeded(u)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			m.Failed(u, err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", u, err))
+	}
+	if len(errs) == 0 {
+		return errors.New("no mirrors")
+	}
+	return errors.Join(errs...)
+}
+
+// Fetch fetches the file name from the first mirror that succeeds.
+func (m *Mirrors) Fetch(ctx context.Context, name string, offset int64) (*File, error) {
+	var f *File
+	err := m.Do(ctx, func(u *URI) error {
+		var err error
+		f, err = u.Fetch(ctx, name, offset)
+		return err
+	})
+	return f, err
+}
+
+// Freshest returns the mirror with the most recent repodata for arch.
+//
+// Mirrors that fail or do not report a modification time are ignored.
+func (m *Mirrors) Freshest(ctx context.Context, arch string) (*URI, error) {
+	var (
+		best    *URI
+		modTime time.Time
+		errs    []error
+	)
+	for _, u := range m.URIs() {
+		fi, err := u.Stat(ctx, fmt.Sprintf("%s-repodata", arch))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				m.Failed(u, err)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+		m.Succeeded(u)
+		if fi.ModTime().IsZero() {
+			errs = append(errs, fmt.Errorf("%s: no modification time", u))
+			continue
+		}
+		if best == nil || fi.ModTime().After(modTime) {
+			best, modTime = u, fi.ModTime()
+		}
+	}
+	if best == nil {
+		return nil, errors.Join(append([]error{errors.New("no mirror available")}, errs...)...)
+	}
+	return best, nil
+}
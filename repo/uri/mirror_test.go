@@ -0,0 +1,140 @@
+package uri
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMirrorList(t *testing.T) {
+	list := `# mirrors
+https://repo-fi.voidlinux.org/current
+
+repository=https://repo-de.voidlinux.org/current
+/hostdir/binpkgs
+`
+	m, err := ParseMirrorList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"https://repo-fi.voidlinux.org/current",
+		"https://repo-de.voidlinux.org/current",
+		"/hostdir/binpkgs",
+	}
+	uris := m.URIs()
+	if len(uris) != len(expect) {
+		t.Fatalf("expected %d mirrors, got %d", len(expect), len(uris))
+	}
+	for i := range expect {
+		if uris[i].String() != expect[i] {
+			t.Errorf("expected %q, got %q", expect[i], uris[i])
+		}
+	}
+	if _, err := ParseMirrorList(strings.NewReader("torrent://foo\n")); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+	if _, err := ParseMirrorList(strings.NewReader("# empty\n")); err == nil {
+		t.Error("expected error for empty mirror list")
+	}
+}
+
+func newMirror(t *testing.T, modTime time.Time, fail bool) *URI {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, "x86_64-repodata", modTime, strings.NewReader("repodata"))
+	}))
+	t.Cleanup(srv.Close)
+	u, err := Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestMirrorsFailover(t *testing.T) {
+	now := time.Now()
+	broken := newMirror(t, now, true)
+	good := newMirror(t, now, false)
+	m := NewMirrors(broken, good)
+	m.now = func() time.Time { return now }
+
+	f, err := m.Fetch(context.Background(), "x86_64-repodata", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	st := m.Status()
+	if st[0].Failures != 1 || st[0].LastError == nil || st[1].Failures != 0 || st[1].LastSuccess != now {
+		t.Fatalf("unexpected mirror status: %+v", st)
+	}
+	if uris := m.URIs(); uris[0] != good || uris[1] != broken {
+		t.Fatalf("failed mirror is not skipped: %v", uris)
+	}
+	m.now = func() time.Time { return now.Add(DefaultBackoff) }
+	if uris := m.URIs(); uris[0] != broken {
+		t.Fatalf("failed mirror is still skipped after backoff: %v", uris)
+	}
+
+	err = m.Do(context.Background(), func(u *URI) error { return ErrStale })
+	if !errors.Is(err, ErrStale) {
+		t.Fatalf("expected ErrStale, got %v", err)
+	}
+
+	// missing files are not mirror failures
+	m = NewMirrors(good)
+	err = m.Do(context.Background(), func(u *URI) error { return fs.ErrNotExist })
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if st := m.Status(); st[0].Failures != 0 {
+		t.Fatalf("missing file was recorded as failure: %+v", st[0])
+	}
+}
+
+func TestMirrorsZero(t *testing.T) {
+	u, err := Parse("https://example.org/current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Mirrors{mirrors: []*MirrorStatus{{URI: u}}}
+	m.Failed(u, ErrStale)
+	if uris := m.URIs(); len(uris) != 1 || uris[0] != u {
+		t.Fatalf("unexpected mirrors %v", uris)
+	}
+	m.Succeeded(u)
+	if st := m.Status(); st[0].Failures != 0 || st[0].LastSuccess.IsZero() {
+		t.Fatalf("unexpected mirror status: %+v", st[0])
+	}
+}
+
+func TestMirrorsFreshest(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	old := newMirror(t, now.Add(-time.Hour), false)
+	fresh := newMirror(t, now, false)
+	broken := newMirror(t, now, true)
+	m := NewMirrors(old, broken, fresh)
+	u, err := m.Freshest(context.Background(), "x86_64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != fresh {
+		t.Fatalf("expected %s, got %s", fresh, u)
+	}
+	if st := m.Status(); st[1].Failures != 1 {
+		t.Fatalf("expected broken mirror to be marked as failed: %+v", st[1])
+	}
+	// mirrors without modification time are ignored
+	m = NewMirrors(newMirror(t, time.Time{}, false))
+	if u, err := m.Freshest(context.Background(), "x86_64"); err == nil {
+		t.Fatalf("expected error for mirror without modification time, got %s", u)
+	}
+}
@@ -0,0 +1,85 @@
+package uri
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// A Fetcher fetches files from repositories.
+//
+// Fetchers are registered for URI schemes with Register, URI.Fetch and
+// URI.Stat dispatch to the fetcher of the URIs scheme.
+type Fetcher interface {
+	// Fetch opens the file name relative to the repository URI for reading,
+	// starting at offset, see URI.Fetch.
+	Fetch(ctx context.Context, u *URI, name string, offset int64) (*File, error)
+	// Stat returns the file info of the file name relative to the repository URI.
+	Stat(ctx context.Context, u *URI, name string) (fs.FileInfo, error)
+}
+
+// scheme is a registered repository scheme
+type scheme struct {
+	remote  bool
+	fetcher Fetcher
+}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]scheme{
+		"":      {false, LocalFetcher{}},
+		"file":  {false, LocalFetcher{}},
+		"http":  {true, &HTTPFetcher{}},
+		"https": {true, &HTTPFetcher{}},
+		// ftp repositories are supported by xbps, but there is no ftp
+		// fetcher unless one is registered.
+		"ftp": {true, nil},
+	}
+)
+
+// Register registers the fetcher for the URI scheme, replacing existing fetchers.
+//
+// The repository data of remote repositories is stored in the cache directory,
+// local repositories are read in place.
+func Register(name string, remote bool, f Fetcher) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[name] = scheme{remote, f}
+}
+
+// Unregister removes the URI scheme.
+func Unregister(name string) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	delete(schemes, name)
+}
+
+// Schemes returns the sorted names of the supported URI schemes.
+func Schemes() []string {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	res := make([]string, 0, len(schemes))
+	for name := range schemes {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func lookupScheme(name string) (scheme, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	s, ok := schemes[name]
+	return s, ok
+}
+
+// Fetcher returns the fetcher for the URIs scheme
+func (u *URI) Fetcher() (Fetcher, error) {
+	s, ok := lookupScheme(u.Scheme)
+	if !ok || s.fetcher == nil {
+		return nil, fmt.Errorf("repo scheme not supported: %s", u.Scheme)
+	}
+	return s.fetcher, nil
+}
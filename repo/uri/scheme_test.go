@@ -0,0 +1,92 @@
+package uri
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"testing"
+	"time"
+)
+
+// memFetcher is a fake transport serving files from memory
+type memFetcher map[string]string
+
+type memFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) Mode() fs.FileMode  { return 0o444 }
+func (fi memFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi memFileInfo) IsDir() bool        { return false }
+func (fi memFileInfo) Sys() any           { return nil }
+
+func (m memFetcher) Fetch(ctx context.Context, u *URI, name string, offset int64) (*File, error) {
+	s, ok := m[u.Host+"/"+name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	rd := io.NopCloser(bytes.NewReader([]byte(s[offset:])))
+	return &File{ReadCloser: rd, Offset: offset, Size: int64(len(s))}, nil
+}
+
+func (m memFetcher) Stat(ctx context.Context, u *URI, name string) (fs.FileInfo, error) {
+	s, ok := m[u.Host+"/"+name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return memFileInfo{name, int64(len(s))}, nil
+}
+
+func TestRegister(t *testing.T) {
+	if _, err := Parse("mem://repo"); err == nil {
+		t.Fatal("expected unregistered scheme to fail")
+	}
+	Register("mem", true, memFetcher{"repo/x86_64-repodata": "repodata"})
+	defer Unregister("mem")
+	if !slices.Contains(Schemes(), "mem") {
+		t.Fatalf("mem is not in %v", Schemes())
+	}
+	u, err := Parse("mem://repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.IsRemote() {
+		t.Fatal("expected mem scheme to be remote")
+	}
+	res, err := u.Repodata("x86_64", "/var/cache/xbps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "/var/cache/xbps/mem___repo/x86_64-repodata"; res != expect {
+		t.Fatalf("expected %q, got %q", expect, res)
+	}
+	f, err := u.Fetch(context.Background(), "x86_64-repodata", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf, _ := io.ReadAll(f); string(buf) != "data" {
+		t.Fatalf("unexpected content %q", buf)
+	}
+	if fi, err := u.Stat(context.Background(), "x86_64-repodata"); err != nil || fi.Size() != 8 {
+		t.Fatalf("unexpected stat result %v, %v", fi, err)
+	}
+	if _, err := u.Fetch(context.Background(), "aarch64-repodata", 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestUnsupportedFetcher(t *testing.T) {
+	u, err := Parse("ftp://alpha.de.repo.voidlinux.org/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Fetch(context.Background(), "x86_64-repodata", 0); err == nil {
+		t.Fatal("expected ftp without fetcher to fail")
+	}
+}
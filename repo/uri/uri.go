@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
-	"slices"
 	"strings"
 )
 
@@ -22,24 +21,8 @@ func Parse(rawuri string) (*URI, error) {
 	return uri, nil
 }
 
-// supported remote repository schemes
-var remoteSchemes = []string{
-	"ftp",
-	"http",
-	"https",
-}
-
-// supported local repository schemes
-var localSchemes = []string{
-	"file",
-	"",
-}
-
 func (u *URI) isSupported() (bool, error) {
-	if slices.Contains(remoteSchemes, u.Scheme) {
-		return true, nil
-	}
-	if slices.Contains(localSchemes, u.Scheme) {
+	if _, ok := lookupScheme(u.Scheme); ok {
 		return true, nil
 	}
 	return false, fmt.Errorf("scheme is not supported: %q", u.Scheme)
@@ -47,7 +30,8 @@ func (u *URI) isSupported() (bool, error) {
 
 // IsRemote returns true if the repository URI is remote
 func (u *URI) IsRemote() bool {
-	return slices.Contains(remoteSchemes, u.Scheme)
+	s, ok := lookupScheme(u.Scheme)
+	return ok && s.remote
 }
 
 // String returns the the url as string
@@ -65,16 +49,16 @@ func (u *URI) CacheString() string {
 
 // Repodata returns the repodata path for arch either in its directory or the cache directory
 func (u *URI) Repodata(arch, cachedir string) (string, error) {
-	switch u.Scheme {
-	case "file", "":
+	s, ok := lookupScheme(u.Scheme)
+	switch {
+	case !ok:
+		return "", fmt.Errorf("repo scheme not supported: %s", u.Scheme)
+	case !s.remote:
 		return filepath.Join(u.Path, fmt.Sprintf("%s-repodata", arch)), nil
-	case "http", "https":
-		if cachedir != "" {
-			return filepath.Join(cachedir, u.CacheString(), fmt.Sprintf("%s-repodata", arch)), nil
-		}
-		return "", fmt.Errorf("repo scheme not supported without cachedir: %s", u.Scheme)
+	case cachedir != "":
+		return filepath.Join(cachedir, u.CacheString(), fmt.Sprintf("%s-repodata", arch)), nil
 	default:
-		return "", fmt.Errorf("repo scheme not supported: %s", u.Scheme)
+		return "", fmt.Errorf("repo scheme not supported without cachedir: %s", u.Scheme)
 	}
 }
 
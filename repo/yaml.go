@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.+-]*$`)
+
+// yamlKey returns the key plain if it can not be mistaken for another type
+func yamlKey(k string) string {
+	switch strings.ToLower(k) {
+	case "y", "n", "yes", "no", "on", "off", "true", "false", "null":
+	default:
+		if plainKey.MatchString(k) {
+			return k
+		}
+	}
+	return yamlString(k)
+}
+
+// yamlString returns s as double quoted scalar, JSON strings are valid
+// YAML double quoted scalars.
+func yamlString(s string) string {
+	buf, _ := marshal(s)
+	return string(buf)
+}
+
+// yamlScalar returns the scalar or empty collection v and true
+func yamlScalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "null", true
+	case bool:
+		if v {
+			return "true", true
+		}
+		return "false", true
+	case json.Number:
+		return v.String(), true
+	case string:
+		return yamlString(v), true
+	case map[string]any:
+		return "{}", len(v) == 0
+	case []any:
+		return "[]", len(v) == 0
+	}
+	return "", false
+}
+
+// writeYAML writes the decoded JSON value v in block style at indent
+func writeYAML(out *bytes.Buffer, v any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	if s, ok := yamlScalar(v); ok {
+		out.WriteString(pad + s + "\n")
+		return
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if s, ok := yamlScalar(v[k]); ok {
+				out.WriteString(pad + yamlKey(k) + ": " + s + "\n")
+				continue
+			}
+			out.WriteString(pad + yamlKey(k) + ":\n")
+			writeYAML(out, v[k], indent+2)
+		}
+	case []any:
+		for _, e := range v {
+			// render the item one level deeper and replace its indentation
+			// on the first line with the sequence indicator
+			var item bytes.Buffer
+			writeYAML(&item, e, indent+2)
+			out.WriteString(pad + "- " + item.String()[indent+2:])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/Duncaen/go-xbps/license"
+)
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     []cdxTool     `json:"tools"`
+	Component *cdxComponent `json:"component,omitempty"`
+}
+
+type cdxTool struct {
+	Name string `json:"name"`
+}
+
+type cdxComponent struct {
+	BOMRef             string         `json:"bom-ref,omitempty"`
+	Type               string         `json:"type"`
+	Name               string         `json:"name"`
+	Version            string         `json:"version,omitempty"`
+	Description        string         `json:"description,omitempty"`
+	Author             string         `json:"author,omitempty"`
+	Hashes             []cdxHash      `json:"hashes,omitempty"`
+	Licenses           []cdxLicense   `json:"licenses,omitempty"`
+	PURL               string         `json:"purl,omitempty"`
+	ExternalReferences []cdxReference `json:"externalReferences,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cdxReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteCycloneDX writes the document as CycloneDX 1.5 JSON
+func (s *SBOM) WriteCycloneDX(w io.Writer) error {
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + s.uuid(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: s.Created.UTC().Format(time.RFC3339),
+			Tools:     []cdxTool{{Name: Tool}},
+		},
+		Components:   []cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+	if s.Name != "" {
+		bom.Metadata.Component = &cdxComponent{Type: "operating-system", Name: s.Name}
+	}
+	refs := make([]string, len(s.Packages))
+	for i := range s.Packages {
+		pkg := &s.Packages[i]
+		n, v := name(pkg)
+		refs[i] = PURL(pkg)
+		c := cdxComponent{
+			BOMRef:      refs[i],
+			Type:        "library",
+			Name:        n,
+			Version:     v,
+			Description: pkg.ShortDesc,
+			Author:      pkg.Maintainer,
+			PURL:        refs[i],
+		}
+		if pkg.FilenameSHA256 != "" {
+			c.Hashes = []cdxHash{{Alg: "SHA-256", Content: pkg.FilenameSHA256}}
+		}
+		if e, err := license.Parse(pkg.License); err == nil {
+			c.Licenses = []cdxLicense{{Expression: e.String()}}
+		}
+		if pkg.Homepage != "" {
+			c.ExternalReferences = []cdxReference{{Type: "website", URL: pkg.Homepage}}
+		}
+		bom.Components = append(bom.Components, c)
+	}
+	for i, deps := range s.dependencies() {
+		d := cdxDependency{Ref: refs[i], DependsOn: []string{}}
+		for _, j := range deps {
+			d.DependsOn = append(d.DependsOn, refs[j])
+		}
+		bom.Dependencies = append(bom.Dependencies, d)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
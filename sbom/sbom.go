@@ -0,0 +1,122 @@
+// Package sbom implements exporting software bills of materials of
+// installed packages and repositories as SPDX 2.3 and CycloneDX JSON.
+//
+// Documents are built from the package metadata only, without network
+// access, and the output is deterministic for a fixed creation time.
+package sbom
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+// Tool is the tool name recorded in documents
+const Tool = "go-xbps"
+
+// SBOM is a software bill of materials of a set of packages
+type SBOM struct {
+	// Name is the name of the document, like the name of an image
+	Name string
+	// Created is the creation time of the document
+	Created time.Time
+	// Packages are the packages sorted by pkgver
+	Packages []repo.Package
+}
+
+// New returns a bill of materials of the packages
+func New(name string, pkgs []repo.Package) *SBOM {
+	s := &SBOM{Name: name, Created: time.Now().UTC(), Packages: append([]repo.Package(nil), pkgs...)}
+	sort.Slice(s.Packages, func(i, j int) bool { return s.Packages[i].PkgVer < s.Packages[j].PkgVer })
+	return s
+}
+
+// FromPkgDB returns a bill of materials of the installed packages
+func FromPkgDB(name string, db *pkgdb.DB) *SBOM {
+	var pkgs []repo.Package
+	for _, pkg := range db.Packages {
+		if pkg.State == pkgdb.StateInstalled {
+			pkgs = append(pkgs, pkg.Package)
+		}
+	}
+	return New(name, pkgs)
+}
+
+// FromRepository returns a bill of materials of the repository index
+func FromRepository(name string, r *repo.Repository) *SBOM {
+	pkgs := make([]repo.Package, 0, len(r.Index))
+	for _, pkg := range r.Index {
+		pkgs = append(pkgs, pkg)
+	}
+	return New(name, pkgs)
+}
+
+// PURL returns the package URL of the package
+func PURL(pkg *repo.Package) string {
+	pv, _ := pkgver.Parse(pkg.PkgVer)
+	s := fmt.Sprintf("pkg:xbps/%s", url.PathEscape(pv.Name))
+	if pv.Version != "" {
+		s += "@" + url.PathEscape(pv.Version)
+	}
+	if pkg.Architecture != "" {
+		s += "?arch=" + url.QueryEscape(pkg.Architecture)
+	}
+	return s
+}
+
+// name returns the package name and version
+func name(pkg *repo.Package) (string, string) {
+	pv, _ := pkgver.Parse(pkg.PkgVer)
+	return pv.Name, pv.Version
+}
+
+// dependencies returns the indices of the run dependencies of each
+// package, dependencies not in the set are omitted.
+func (s *SBOM) dependencies() [][]int {
+	providers := make(map[string]int)
+	for i := range s.Packages {
+		for _, p := range s.Packages[i].Provides {
+			if pv, err := pkgver.Parse(p); err == nil {
+				providers[pv.Name] = i
+			}
+		}
+	}
+	for i := range s.Packages {
+		n, _ := name(&s.Packages[i])
+		providers[n] = i
+	}
+	res := make([][]int, len(s.Packages))
+	for i, pkg := range s.Packages {
+		seen := make(map[int]bool)
+		for _, dep := range pkg.RunDepends {
+			pv, err := pkgver.Parse(dep)
+			if err != nil {
+				continue
+			}
+			if j, ok := providers[pv.Name]; ok && j != i && !seen[j] {
+				seen[j] = true
+				res[i] = append(res[i], j)
+			}
+		}
+	}
+	return res
+}
+
+// uuid returns a name based UUID of the document contents
+func (s *SBOM) uuid() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", s.Name, s.Created.Format(time.RFC3339))
+	for _, pkg := range s.Packages {
+		fmt.Fprintf(h, "%s %s\n", pkg.PkgVer, pkg.Architecture)
+	}
+	b := h.Sum(nil)[:16]
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,126 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func testSBOM() *SBOM {
+	db := &pkgdb.DB{Packages: map[string]pkgdb.Package{
+		"foo": {State: pkgdb.StateInstalled, Package: repo.Package{
+			PkgVer:         "foo-1.0_1",
+			Architecture:   "x86_64",
+			License:        "MIT, custom:Hybrid",
+			Homepage:       "https://example.org/foo",
+			Maintainer:     "Foo Bar <foo@example.org>",
+			ShortDesc:      "Foo",
+			FilenameSHA256: "abcdef",
+			RunDepends:     []string{"libbar>=1.0_1", "virt>=0", "missing>=0"},
+		}},
+		"libbar": {State: pkgdb.StateInstalled, Package: repo.Package{
+			PkgVer:       "libbar-2.0_1",
+			Architecture: "x86_64",
+			License:      "BSD-2-Clause",
+			Provides:     []string{"virt-1.0_1"},
+		}},
+		"half": {State: pkgdb.StateHalfUnpacked, Package: repo.Package{PkgVer: "half-1.0_1"}},
+	}}
+	s := FromPkgDB("image", db)
+	s.Created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return s
+}
+
+func TestSPDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testSBOM().WriteSPDX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.CreationInfo.Created != "2024-01-02T03:04:05Z" {
+		t.Errorf("unexpected document header %+v", doc)
+	}
+	if len(doc.Packages) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(doc.Packages))
+	}
+	foo := doc.Packages[0]
+	if foo.Name != "foo" || foo.VersionInfo != "1.0_1" || foo.LicenseDeclared != "MIT AND LicenseRef-Hybrid" ||
+		foo.Supplier != "Person: Foo Bar (foo@example.org)" || len(foo.Checksums) != 1 ||
+		foo.ExternalRefs[0].ReferenceLocator != "pkg:xbps/foo@1.0_1?arch=x86_64" {
+		t.Errorf("unexpected package %+v", foo)
+	}
+	if len(doc.ExtractedLicenses) != 1 || doc.ExtractedLicenses[0].LicenseID != "LicenseRef-Hybrid" {
+		t.Errorf("unexpected extracted licenses %+v", doc.ExtractedLicenses)
+	}
+	var depends []string
+	for _, r := range doc.Relationships {
+		if r.RelationshipType == "DEPENDS_ON" {
+			depends = append(depends, r.SPDXElementID+" "+r.RelatedSPDXElement)
+		}
+	}
+	if len(depends) != 1 || depends[0] != "SPDXRef-Package-foo SPDXRef-Package-libbar" {
+		t.Errorf("unexpected dependencies %v", depends)
+	}
+
+	var again bytes.Buffer
+	if err := testSBOM().WriteSPDX(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("output is not deterministic")
+	}
+}
+
+func TestSPDXIDCollision(t *testing.T) {
+	s := &SBOM{Packages: []repo.Package{
+		{PkgVer: "gtk+-2.24_1"},
+		{PkgVer: "gtk--1.0_1"},
+		{PkgVer: "gtk_-1.0_1"},
+	}}
+	var buf bytes.Buffer
+	if err := s.WriteSPDX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range doc.Packages {
+		ids = append(ids, p.SPDXID)
+	}
+	want := []string{"SPDXRef-Package-gtk-", "SPDXRef-Package-gtk--2", "SPDXRef-Package-gtk--3"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected ids %v, got %v", want, ids)
+	}
+}
+
+func TestCycloneDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testSBOM().WriteCycloneDX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var bom cdxBOM
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatal(err)
+	}
+	if bom.BOMFormat != "CycloneDX" || len(bom.Components) != 2 {
+		t.Fatalf("unexpected bom %+v", bom)
+	}
+	foo := bom.Components[0]
+	if foo.PURL != "pkg:xbps/foo@1.0_1?arch=x86_64" || foo.Hashes[0].Content != "abcdef" ||
+		foo.Licenses[0].Expression != "MIT AND LicenseRef-Hybrid" {
+		t.Errorf("unexpected component %+v", foo)
+	}
+	if d := bom.Dependencies[0]; len(d.DependsOn) != 1 || d.DependsOn[0] != "pkg:xbps/libbar@2.0_1?arch=x86_64" {
+		t.Errorf("unexpected dependencies %+v", d)
+	}
+}
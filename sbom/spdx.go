@@ -0,0 +1,192 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/Duncaen/go-xbps/license"
+)
+
+const noAssertion = "NOASSERTION"
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+	ExtractedLicenses []spdxExtracted    `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	Supplier         string            `json:"supplier,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	Homepage         string            `json:"homepage,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Summary          string            `json:"summary,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxExtracted struct {
+	LicenseID     string `json:"licenseId"`
+	Name          string `json:"name"`
+	ExtractedText string `json:"extractedText"`
+}
+
+// spdxID returns an unused SPDX element identifier for the package name.
+//
+// Characters not allowed in identifiers are replaced with "-", names that
+// map to an identifier in used get a counter suffix.
+func spdxID(name string, used map[string]bool) string {
+	base := "SPDXRef-Package-" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+	id := base
+	for i := 2; used[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[id] = true
+	return id
+}
+
+// supplier returns the SPDX supplier of the maintainer
+func supplier(maintainer string) string {
+	addr, err := mail.ParseAddress(maintainer)
+	switch {
+	case maintainer == "":
+		return ""
+	case err != nil:
+		return "Person: " + maintainer
+	case addr.Name == "":
+		return fmt.Sprintf("Person: (%s)", addr.Address)
+	}
+	return fmt.Sprintf("Person: %s (%s)", addr.Name, addr.Address)
+}
+
+// WriteSPDX writes the document as SPDX 2.3 JSON
+func (s *SBOM) WriteSPDX(w io.Writer) error {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              s.Name,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", pathName(s.Name), s.uuid()),
+		CreationInfo: spdxCreationInfo{
+			Created:  s.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + Tool},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	extracted := make(map[string]bool)
+	ids := make([]string, len(s.Packages))
+	used := make(map[string]bool)
+	for i := range s.Packages {
+		pkg := &s.Packages[i]
+		n, v := name(pkg)
+		ids[i] = spdxID(n, used)
+		p := spdxPackage{
+			Name:             n,
+			SPDXID:           ids[i],
+			VersionInfo:      v,
+			Supplier:         supplier(pkg.Maintainer),
+			DownloadLocation: noAssertion,
+			Homepage:         pkg.Homepage,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  noAssertion,
+			CopyrightText:    noAssertion,
+			Summary:          pkg.ShortDesc,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  PURL(pkg),
+			}},
+		}
+		if pkg.FilenameSHA256 != "" {
+			p.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: pkg.FilenameSHA256}}
+		}
+		if e, err := license.Parse(pkg.License); err == nil {
+			p.LicenseDeclared = e.String()
+			for _, l := range license.Licenses(e) {
+				if id := l.SPDXID(); l.Custom && !extracted[id] {
+					extracted[id] = true
+					doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxExtracted{
+						LicenseID:     id,
+						Name:          l.ID,
+						ExtractedText: noAssertion,
+					})
+				}
+			}
+		}
+		doc.Packages = append(doc.Packages, p)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      doc.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: ids[i],
+		})
+	}
+	for i, deps := range s.dependencies() {
+		for _, j := range deps {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID:      ids[i],
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: ids[j],
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// pathName returns the name usable as part of an URL path
+func pathName(name string) string {
+	if name == "" {
+		return "sbom"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == ' ' || r == '#' || r == '?' {
+			return '-'
+		}
+		return r
+	}, name)
+}
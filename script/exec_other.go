@@ -0,0 +1,13 @@
+//go:build !unix
+
+package script
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setup ignores chroot, it is not supported on this platform
+func setup(cmd *exec.Cmd, chroot string) {
+	cmd.WaitDelay = time.Second
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package script
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setup runs the script in its own process group, so that all its
+// processes are killed on timeout, and inside chroot if it is not empty.
+func setup(cmd *exec.Cmd, chroot string) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Chroot: chroot}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+}
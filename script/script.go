@@ -0,0 +1,236 @@
+// Package script implements running the INSTALL and REMOVE scripts of
+// packages like xbps does.
+//
+// Scripts are written to a temporary file in the root directory and run
+// with the shell as:
+//
+//	/bin/sh <script> <action> <pkgname> <version> <update> <conf_file> <arch>
+//
+// where action is pre or post for INSTALL scripts and pre, post or purge
+// for REMOVE scripts and update is yes or no.
+//
+// If the root directory is not / scripts are run inside it, using chroot
+// if the process is privileged, otherwise with the root directory as
+// working directory and the script path relative to it.
+package script
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/pkgver"
+)
+
+// Action is the action a script is run for
+type Action string
+
+const (
+	// ActionPre is run before the package is unpacked or removed
+	ActionPre Action = "pre"
+	// ActionPost is run after the package is unpacked or removed
+	ActionPost Action = "post"
+	// ActionPurge is run by REMOVE scripts when the package is purged
+	ActionPurge Action = "purge"
+)
+
+// ErrTimeout is returned if a script did not finish before the timeout
+var ErrTimeout = errors.New("script timed out")
+
+// Runner runs package scripts
+type Runner struct {
+	// RootDir is the root directory, defaults to /
+	RootDir string
+	// Arch is the architecture passed to scripts, defaults to the
+	// native architecture.
+	Arch string
+	// ConfFile is passed as conf_file argument, defaults to no
+	ConfFile string
+	// Shell is the shell inside the root directory, defaults to /bin/sh
+	Shell string
+	// Env is the environment of scripts, if nil the environment
+	// of the current process is used like xbps does.
+	Env []string
+	// Timeout is the maximum run time of a script, zero means no timeout
+	Timeout time.Duration
+	// Output, if set, receives the script output as it is written
+	Output io.Writer
+	// Chroot forces running scripts with or without chroot, by default
+	// chroot is used if the process is privileged and RootDir is not /.
+	Chroot *bool
+}
+
+// Result is the result of a script run
+type Result struct {
+	// Output is the combined standard output and error of the script
+	Output []byte
+	// ExitCode is the exit status of the script, -1 if it was killed
+	ExitCode int
+	// Duration is the run time of the script
+	Duration time.Duration
+}
+
+// nativeArch maps GOARCH to the xbps architecture names
+var nativeArch = map[string]string{
+	"amd64":   "x86_64",
+	"386":     "i686",
+	"arm64":   "aarch64",
+	"arm":     "armv7l",
+	"ppc64le": "ppc64le",
+	"ppc64":   "ppc64",
+	"ppc":     "ppc",
+	"riscv64": "riscv64",
+}
+
+func (r *Runner) rootdir() string {
+	if r.RootDir == "" {
+		return "/"
+	}
+	return r.RootDir
+}
+
+func (r *Runner) arch() string {
+	if r.Arch != "" {
+		return r.Arch
+	}
+	if arch, ok := nativeArch[runtime.GOARCH]; ok {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
+func (r *Runner) chroot() bool {
+	if r.rootdir() == "/" {
+		return false
+	}
+	if r.Chroot != nil {
+		return *r.Chroot
+	}
+	return os.Geteuid() == 0
+}
+
+// Args returns the arguments a script at path is run with
+func (r *Runner) Args(path string, action Action, pkgname, version string, update bool) []string {
+	shell := r.Shell
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	upd := "no"
+	if update {
+		upd = "yes"
+	}
+	conf := r.ConfFile
+	if conf == "" {
+		conf = "no"
+	}
+	return []string{shell, path, string(action), pkgname, version, upd, conf, r.arch()}
+}
+
+// Run runs the script for the package pkgver.
+//
+// A non-zero exit status is returned as error together with the result.
+func (r *Runner) Run(ctx context.Context, script []byte, action Action, pkg string, update bool) (*Result, error) {
+	pv, err := pkgver.Parse(pkg)
+	if err != nil || pv.Version == "" {
+		return nil, fmt.Errorf("invalid pkgver: %s", pkg)
+	}
+	root := r.rootdir()
+	tmpdir := os.TempDir()
+	if root != "/" {
+		tmpdir = filepath.Join(root, "tmp")
+		if err := os.MkdirAll(tmpdir, 0o1777); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.CreateTemp(tmpdir, ".xbps-script-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(script); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Chmod(0o750); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	path, dir, chroot := f.Name(), "", ""
+	if root != "/" {
+		rel := strings.TrimPrefix(f.Name(), filepath.Clean(root))
+		if r.chroot() {
+			path, dir, chroot = rel, "/", root
+		} else {
+			path, dir = "."+rel, root
+		}
+	}
+
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	args := r.Args(path, action, pv.Name, pv.Version, update)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = r.Env
+	var out bytes.Buffer
+	if r.Output != nil {
+		cmd.Stdout = io.MultiWriter(&out, r.Output)
+	} else {
+		cmd.Stdout = &out
+	}
+	cmd.Stderr = cmd.Stdout
+	setup(cmd, chroot)
+
+	start := time.Now()
+	err = cmd.Run()
+	res := &Result{Output: out.Bytes(), Duration: time.Since(start), ExitCode: -1}
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return res, fmt.Errorf("%s: %s: %w", pkg, action, ErrTimeout)
+	case err != nil:
+		return res, fmt.Errorf("%s: %s script failed: %w", pkg, action, err)
+	}
+	return res, nil
+}
+
+// RunPackage runs the INSTALL or REMOVE script, entry is binpkg.InstallEntry
+// or binpkg.RemoveEntry, of the binary package at path.
+//
+// Packages without the script return a nil result.
+func (r *Runner) RunPackage(ctx context.Context, path, entry string, action Action, update bool) (*Result, error) {
+	meta, err := binpkg.OpenMetadata(path)
+	if err != nil {
+		return nil, err
+	}
+	var script []byte
+	switch entry {
+	case binpkg.InstallEntry:
+		script = meta.Install
+	case binpkg.RemoveEntry:
+		script = meta.Remove
+	default:
+		return nil, fmt.Errorf("not a package script: %s", entry)
+	}
+	if script == nil {
+		return nil, nil
+	}
+	return r.Run(ctx, script, action, meta.Props.PkgVer, update)
+}
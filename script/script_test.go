@@ -0,0 +1,118 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+func testRunner(t *testing.T) *Runner {
+	t.Helper()
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("/bin/sh is required")
+	}
+	chroot := false
+	return &Runner{RootDir: t.TempDir(), Arch: "x86_64", Chroot: &chroot, Env: []string{"FOO=bar"}}
+}
+
+func TestRun(t *testing.T) {
+	r := testRunner(t)
+	script := []byte("#!/bin/sh\necho \"$@\"\necho \"$FOO\"\npwd\necho err >&2\n")
+	res, err := r.Run(context.Background(), script, ActionPost, "foo-32bit-1.0_1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(res.Output)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("unexpected output %q", res.Output)
+	}
+	if lines[0] != "post foo-32bit 1.0_1 yes no x86_64" {
+		t.Errorf("unexpected arguments %q", lines[0])
+	}
+	if lines[1] != "bar" {
+		t.Errorf("unexpected environment %q", lines[1])
+	}
+	root, _ := filepath.EvalSymlinks(r.RootDir)
+	if pwd, _ := filepath.EvalSymlinks(lines[2]); pwd != root {
+		t.Errorf("expected working directory %q, got %q", root, lines[2])
+	}
+	if lines[3] != "err" {
+		t.Errorf("standard error was not captured: %q", lines[3])
+	}
+	if res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %d", res.ExitCode)
+	}
+	entries, _ := os.ReadDir(filepath.Join(r.RootDir, "tmp"))
+	if len(entries) != 0 {
+		t.Errorf("script was not removed")
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	r := testRunner(t)
+	res, err := r.Run(context.Background(), []byte("echo failing; exit 3\n"), ActionPre, "foo-1.0_1", false)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if res.ExitCode != 3 || strings.TrimSpace(string(res.Output)) != "failing" {
+		t.Fatalf("unexpected result %d %q", res.ExitCode, res.Output)
+	}
+	if _, err := r.Run(context.Background(), nil, ActionPre, "foo", false); err == nil {
+		t.Fatal("expected error for invalid pkgver")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := testRunner(t)
+	r.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	_, err := r.Run(context.Background(), []byte("sleep 10 & sleep 10\n"), ActionPre, "foo-1.0_1", false)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("script was not killed")
+	}
+}
+
+func TestRunPackage(t *testing.T) {
+	r := testRunner(t)
+	path := filepath.Join(t.TempDir(), "foo-1.0_1.x86_64.xbps")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := binpkg.NewWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := &binpkg.Metadata{
+		Props:   repo.Package{PkgVer: "foo-1.0_1", Architecture: "x86_64"},
+		Install: []byte("echo install \"$1\"\n"),
+	}
+	if err := w.WriteMetadata(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	res, err := r.RunPackage(context.Background(), path, binpkg.InstallEntry, ActionPre, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(res.Output)) != "install pre" {
+		t.Fatalf("unexpected output %q", res.Output)
+	}
+	if res, err := r.RunPackage(context.Background(), path, binpkg.RemoveEntry, ActionPre, false); res != nil || err != nil {
+		t.Fatalf("expected no result for missing script, got %v, %v", res, err)
+	}
+}
@@ -0,0 +1,276 @@
+// Package triggers implements planning and dry-running the xbps-triggers
+// declared by packages of a transaction.
+//
+// Triggers run from the INSTALL and REMOVE scripts of packages. Like xbps
+// a transaction first runs the scripts of removed packages, then the pre
+// action of all installed packages while they are unpacked and finally
+// the post action of all installed packages while they are configured.
+//
+// DryRun reports the system changes known triggers would make, like
+// system accounts, directories, login shells and python bytecode, without
+// running anything.
+package triggers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+)
+
+// Item is a package of a transaction
+type Item struct {
+	// Meta is the metadata of the package
+	Meta *binpkg.Metadata
+	// Remove is true if the package is removed
+	Remove bool
+	// Update is true if the package is updated
+	Update bool
+}
+
+// Step is a trigger run
+type Step struct {
+	// PkgVer is the package declaring the trigger
+	PkgVer string `json:"pkgver"`
+	// Trigger is the name of the trigger
+	Trigger string `json:"trigger"`
+	// Action is the script action the trigger runs in
+	Action string `json:"action"`
+	// Remove is true if the trigger runs from the REMOVE script
+	Remove bool `json:"remove,omitempty"`
+	// Update is true if the package is updated
+	Update bool `json:"update,omitempty"`
+
+	vars map[string]string
+}
+
+// order is the order of triggers that others depend on, system accounts
+// are created before directories owned by them.
+var order = map[string]int{
+	"system-accounts": 1,
+	"mkdirs":          2,
+}
+
+// triggers returns the triggers the package runs for action in order,
+// the order of the script is kept if it runs the triggers by action.
+func triggers(t *binpkg.Triggers, action string, remove bool) []string {
+	if len(t.Actions) > 0 {
+		return append([]string(nil), t.Actions[action]...)
+	}
+	// only the trigger list is known, use the actions xbps-src generates
+	var names []string
+	for _, name := range t.Names {
+		if defaultAction(name, remove) == action {
+			names = append(names, name)
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := order[names[i]], order[names[j]]
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+	return names
+}
+
+// defaultAction returns the action a trigger runs in
+func defaultAction(name string, remove bool) string {
+	switch {
+	case !remove && name == "system-accounts":
+		return "pre"
+	case remove && name == "pycompile":
+		return "pre"
+	}
+	return "post"
+}
+
+// Plan returns the trigger runs of the transaction in order
+func Plan(items []Item) []Step {
+	var steps []Step
+	add := func(it Item, action string) {
+		script := it.Meta.Install
+		if it.Remove {
+			script = it.Meta.Remove
+		}
+		t := binpkg.ParseTriggers(script)
+		for _, name := range triggers(t, action, it.Remove) {
+			steps = append(steps, Step{
+				PkgVer:  it.Meta.Props.PkgVer,
+				Trigger: name,
+				Action:  action,
+				Remove:  it.Remove,
+				Update:  it.Update,
+				vars:    t.Vars,
+			})
+		}
+	}
+	for _, it := range items {
+		if it.Remove {
+			add(it, "pre")
+			add(it, "post")
+		}
+	}
+	for _, action := range []string{"pre", "post"} {
+		for _, it := range items {
+			if !it.Remove {
+				add(it, action)
+			}
+		}
+	}
+	return steps
+}
+
+// Kind is the kind of system change
+type Kind string
+
+// Kinds of system changes
+const (
+	KindAccount  Kind = "account"
+	KindGroup    Kind = "group"
+	KindDir      Kind = "dir"
+	KindShell    Kind = "shell"
+	KindBytecode Kind = "bytecode"
+	// KindRun is a trigger without known effects
+	KindRun Kind = "run"
+)
+
+// Effect is a system change a trigger run would make
+type Effect struct {
+	Step
+	// Kind is the kind of the changed object
+	Kind Kind `json:"kind"`
+	// Change is the change, like create or remove
+	Change string `json:"change"`
+	// Target is the changed object, like an account name or path
+	Target string `json:"target"`
+	// Detail are additional details of the change
+	Detail string `json:"detail,omitempty"`
+}
+
+func (e Effect) String() string {
+	if e.Kind == KindRun {
+		return fmt.Sprintf("%s: %s %s: run", e.PkgVer, e.Trigger, e.Action)
+	}
+	s := fmt.Sprintf("%s: %s %s: %s %s %s", e.PkgVer, e.Trigger, e.Action, e.Change, e.Kind, e.Target)
+	if e.Detail != "" {
+		s += " (" + e.Detail + ")"
+	}
+	return s
+}
+
+// DryRun returns the system changes of the transaction in order
+func DryRun(items []Item) []Effect {
+	var res []Effect
+	for _, step := range Plan(items) {
+		res = append(res, step.effects()...)
+	}
+	return res
+}
+
+// effects returns the changes of a trigger run
+func (s Step) effects() []Effect {
+	effect := func(kind Kind, change, target, detail string) Effect {
+		return Effect{Step: s, Kind: kind, Change: change, Target: target, Detail: detail}
+	}
+	var res []Effect
+	switch s.Trigger {
+	case "system-accounts":
+		if s.Remove || s.Action != "pre" {
+			// accounts are never removed
+			return nil
+		}
+		for _, g := range strings.Fields(s.vars["system_groups"]) {
+			name, gid, _ := strings.Cut(g, ":")
+			res = append(res, effect(KindGroup, "create", name, field("gid", gid)))
+		}
+		for _, a := range strings.Fields(s.vars["system_accounts"]) {
+			name, uid, _ := strings.Cut(a, ":")
+			pgroup := s.vars[name+"_pgroup"]
+			if pgroup == "" {
+				pgroup = name
+				res = append(res, effect(KindGroup, "create", name, field("gid", uid)))
+			}
+			home := s.vars[name+"_homedir"]
+			if home == "" {
+				home = "/var/empty"
+			}
+			shell := s.vars[name+"_shell"]
+			if shell == "" {
+				shell = "/sbin/nologin"
+			}
+			detail := strings.Join(nonEmpty(
+				field("uid", uid),
+				field("group", pgroup),
+				field("groups", s.vars[name+"_groups"]),
+				field("home", home),
+				field("shell", shell),
+			), " ")
+			res = append(res, effect(KindAccount, "create", name, detail))
+		}
+	case "mkdirs":
+		if s.Action != "post" {
+			return nil
+		}
+		f := strings.Fields(s.vars["make_dirs"])
+		for i := 0; i+3 < len(f); i += 4 {
+			if s.Remove {
+				res = append(res, effect(KindDir, "remove", f[i], "if empty"))
+			} else {
+				res = append(res, effect(KindDir, "create", f[i], fmt.Sprintf("mode %s owner %s:%s", f[i+1], f[i+2], f[i+3])))
+			}
+		}
+	case "register-shell":
+		if s.Action != "post" {
+			return nil
+		}
+		change := "register"
+		if s.Remove {
+			change = "unregister"
+		}
+		for _, shell := range strings.Fields(s.vars["register_shell"]) {
+			res = append(res, effect(KindShell, change, shell, "/etc/shells"))
+		}
+	case "pycompile":
+		if s.Action != defaultAction(s.Trigger, s.Remove) {
+			return nil
+		}
+		change := "compile"
+		if s.Remove {
+			change = "remove"
+		}
+		version := s.vars["pycompile_version"]
+		if version == "" {
+			// the default python version is not known without running the trigger
+			version = "3*"
+		}
+		for _, dir := range strings.Fields(s.vars["pycompile_dirs"]) {
+			res = append(res, effect(KindBytecode, change, dir, ""))
+		}
+		for _, mod := range strings.Fields(s.vars["pycompile_module"]) {
+			target := fmt.Sprintf("/usr/lib/python%s/site-packages/%s", version, mod)
+			res = append(res, effect(KindBytecode, change, target, ""))
+		}
+	default:
+		res = append(res, effect(KindRun, "run", s.Trigger, ""))
+	}
+	return res
+}
+
+func field(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	return name + " " + value
+}
+
+func nonEmpty(s ...string) []string {
+	var res []string
+	for _, v := range s {
+		if v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
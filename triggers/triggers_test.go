@@ -0,0 +1,83 @@
+package triggers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/binpkg"
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+const fooInstall = `#!/bin/sh
+export system_accounts="_foo:123"
+export _foo_homedir="/var/lib/foo"
+export make_dirs="/var/lib/foo 0750 _foo _foo"
+case "${ACTION}" in
+pre)
+	${TRIGGERSDIR}/system-accounts run ${ACTION} ${PKGNAME} ${VERSION} ${UPDATE} ${CONF_FILE}
+	;;
+post)
+	${TRIGGERSDIR}/mkdirs run ${ACTION} ${PKGNAME} ${VERSION} ${UPDATE} ${CONF_FILE}
+	${TRIGGERSDIR}/system-accounts run ${ACTION} ${PKGNAME} ${VERSION} ${UPDATE} ${CONF_FILE}
+	;;
+esac
+`
+
+func meta(pkgver, install, remove string) *binpkg.Metadata {
+	return &binpkg.Metadata{
+		Props:   repo.Package{PkgVer: pkgver},
+		Install: []byte(install),
+		Remove:  []byte(remove),
+	}
+}
+
+func TestPlan(t *testing.T) {
+	items := []Item{
+		{Meta: meta("foo-1.0_1", fooInstall, "")},
+		{Meta: meta("sh-1.0_1", "export triggers=\"register-shell gtk-icon-cache\"\nexport register_shell=\"/bin/foosh\"\n", "")},
+		{Meta: meta("oldsh-1.0_1", "", "export triggers=\"register-shell\"\nexport register_shell=\"/bin/oldsh\"\n"), Remove: true},
+		{Meta: meta("bar-1.0_1", "", "export triggers=\"mkdirs system-accounts\"\n"), Remove: true},
+	}
+	var got []string
+	for _, s := range Plan(items) {
+		got = append(got, s.PkgVer+" "+s.Action+" "+s.Trigger)
+	}
+	expect := []string{
+		"oldsh-1.0_1 post register-shell",
+		// without actions system accounts are handled first
+		"bar-1.0_1 post system-accounts",
+		"bar-1.0_1 post mkdirs",
+		"foo-1.0_1 pre system-accounts",
+		// the script order is kept
+		"foo-1.0_1 post mkdirs",
+		"foo-1.0_1 post system-accounts",
+		"sh-1.0_1 post register-shell",
+		"sh-1.0_1 post gtk-icon-cache",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected plan:\n%v\ngot:\n%v", expect, got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	items := []Item{
+		{Meta: meta("foo-1.0_1", fooInstall, "")},
+		{Meta: meta("sh-1.0_1", "export triggers=\"register-shell gtk-icon-cache\"\nexport register_shell=\"/bin/foosh\"\n", "")},
+		{Meta: meta("oldsh-1.0_1", "", "export triggers=\"register-shell\"\nexport register_shell=\"/bin/oldsh\"\n"), Remove: true},
+	}
+	var got []string
+	for _, e := range DryRun(items) {
+		got = append(got, e.String())
+	}
+	expect := []string{
+		"oldsh-1.0_1: register-shell post: unregister shell /bin/oldsh (/etc/shells)",
+		"foo-1.0_1: system-accounts pre: create group _foo (gid 123)",
+		"foo-1.0_1: system-accounts pre: create account _foo (uid 123 group _foo home /var/lib/foo shell /sbin/nologin)",
+		"foo-1.0_1: mkdirs post: create dir /var/lib/foo (mode 0750 owner _foo:_foo)",
+		"sh-1.0_1: register-shell post: register shell /bin/foosh (/etc/shells)",
+		"sh-1.0_1: gtk-icon-cache post: run",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected effects:\n%v\ngot:\n%v", expect, got)
+	}
+}
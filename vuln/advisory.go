@@ -0,0 +1,317 @@
+package vuln
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/version"
+)
+
+// Advisory is a vulnerability advisory, a subset of the OSV schema
+type Advisory struct {
+	ID               string           `json:"id"`
+	Aliases          []string         `json:"aliases,omitempty"`
+	Summary          string           `json:"summary,omitempty"`
+	Severity         []Severity       `json:"severity,omitempty"`
+	Affected         []Affected       `json:"affected"`
+	DatabaseSpecific DatabaseSpecific `json:"database_specific,omitempty"`
+}
+
+// Severity is a severity score, like a CVSS vector
+type Severity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// DatabaseSpecific are the OSV database specific fields
+type DatabaseSpecific struct {
+	Severity string `json:"severity,omitempty"`
+}
+
+// Package is an affected package
+type Package struct {
+	Ecosystem string `json:"ecosystem,omitempty"`
+	Name      string `json:"name"`
+}
+
+// Affected are the affected versions of a package
+type Affected struct {
+	Package          Package          `json:"package"`
+	Ranges           []Range          `json:"ranges,omitempty"`
+	Versions         []string         `json:"versions,omitempty"`
+	DatabaseSpecific DatabaseSpecific `json:"database_specific,omitempty"`
+	// Pattern is a package pattern of affected versions, like foo<1.2_1
+	Pattern string `json:"-"`
+}
+
+// Range is a range of affected versions
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+// Event is a version introducing or fixing a vulnerability
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+func (e Event) version() string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	}
+	return e.LastAffected
+}
+
+// cmp compares versions, advisory versions without revision match all
+// revisions of the version, like 1.0 matches 1.0_1 but not 1.0.1_1.
+func cmp(v, adv string) int {
+	if !strings.Contains(adv, "_") {
+		if i := strings.LastIndexByte(v, '_'); i != -1 {
+			v = v[:i]
+		}
+	}
+	return version.Cmp(v, adv)
+}
+
+// matchPattern checks the version against the package pattern with the
+// revision rule of cmp, glob patterns are matched against the pkgver.
+func matchPattern(name, v, pattern string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		return pkgver.Match(name+"-"+v, pattern)
+	}
+	pv, err := pkgver.Parse(pattern)
+	if err != nil || pv.Name != name {
+		return false
+	}
+	if pv.Pattern == "" {
+		return pv.Version == "" || cmp(v, pv.Version) == 0
+	}
+	rel := pv.Pattern
+	for rel != "" {
+		op := rel[:1]
+		if len(rel) > 1 && rel[1] == '=' {
+			op = rel[:2]
+		}
+		rel = rel[len(op):]
+		end := strings.IndexAny(rel, "<>=!")
+		if end == -1 {
+			end = len(rel)
+		}
+		want := rel[:end]
+		rel = rel[end:]
+		if want == "" {
+			return false
+		}
+		c := cmp(v, want)
+		var ok bool
+		switch op {
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		case "==":
+			ok = c == 0
+		case "!=":
+			ok = c != 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Affects returns true if the version of the package is affected
+func (a *Affected) Affects(v string) bool {
+	if a.Pattern != "" {
+		return matchPattern(a.Package.Name, v, a.Pattern)
+	}
+	for _, av := range a.Versions {
+		if cmp(v, av) == 0 {
+			return true
+		}
+	}
+	for _, r := range a.Ranges {
+		if r.Type == "GIT" {
+			continue
+		}
+		events := append([]Event(nil), r.Events...)
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].Introduced == "0" || events[j].Introduced == "0" {
+				return events[i].Introduced == "0" && events[j].Introduced != "0"
+			}
+			return version.Cmp(events[i].version(), events[j].version()) < 0
+		})
+		affected := false
+		for _, e := range events {
+			switch {
+			case e.Introduced != "":
+				if e.Introduced == "0" || cmp(v, e.Introduced) >= 0 {
+					affected = true
+				}
+			case e.Fixed != "":
+				if cmp(v, e.Fixed) >= 0 {
+					affected = false
+				}
+			case e.LastAffected != "":
+				if cmp(v, e.LastAffected) > 0 {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return true
+		}
+	}
+	return false
+}
+
+// Fixed returns the versions fixing the vulnerability
+func (a *Affected) Fixed() []string {
+	var res []string
+	for _, r := range a.Ranges {
+		for _, e := range r.Events {
+			if e.Fixed != "" {
+				res = append(res, e.Fixed)
+			}
+		}
+	}
+	if a.Pattern != "" {
+		pv, _ := pkgver.Parse(a.Pattern)
+		if i := strings.Index(pv.Pattern, "<"); i != -1 && !strings.HasPrefix(pv.Pattern[i+1:], "=") {
+			fixed := pv.Pattern[i+1:]
+			if j := strings.IndexAny(fixed, "<>=!"); j != -1 {
+				fixed = fixed[:j]
+			}
+			res = append(res, fixed)
+		}
+	}
+	return res
+}
+
+// Level returns the severity of the advisory for the affected package,
+// the database specific severity like HIGH or the severity score.
+func (a *Advisory) Level(affected *Affected) string {
+	switch {
+	case affected != nil && affected.DatabaseSpecific.Severity != "":
+		return affected.DatabaseSpecific.Severity
+	case a.DatabaseSpecific.Severity != "":
+		return a.DatabaseSpecific.Severity
+	case len(a.Severity) > 0:
+		return a.Severity[0].Score
+	}
+	return ""
+}
+
+// ReadOSV reads OSV advisories, either a single advisory or a list
+func ReadOSV(r io.Reader) ([]Advisory, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	buf = bytes.TrimSpace(buf)
+	if len(buf) > 0 && buf[0] == '[' {
+		var res []Advisory
+		if err := json.Unmarshal(buf, &res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	var a Advisory
+	if err := json.Unmarshal(buf, &a); err != nil {
+		return nil, err
+	}
+	return []Advisory{a}, nil
+}
+
+// ReadList reads a simple advisory list.
+//
+// Each line contains an advisory identifier, a package pattern of the
+// affected versions and optionally the severity and a summary:
+//
+//	# comment
+//	CVE-2023-0286 openssl<3.0.8_1 high X.400 address type confusion
+//	CVE-2023-38545 libcurl>=7.69.0<8.4.0_1 critical
+func ReadList(r io.Reader) ([]Advisory, error) {
+	var res []Advisory
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			return nil, fmt.Errorf("line %d: missing package pattern", n)
+		}
+		pv, err := pkgver.Parse(f[1])
+		if err != nil || pv.Version != "" {
+			return nil, fmt.Errorf("line %d: invalid package pattern %q", n, f[1])
+		}
+		a := Advisory{ID: f[0], Affected: []Affected{{Package: Package{Name: pv.Name}, Pattern: f[1]}}}
+		if len(f) > 2 {
+			a.DatabaseSpecific.Severity = strings.ToUpper(f[2])
+		}
+		if len(f) > 3 {
+			a.Summary = strings.Join(f[3:], " ")
+		}
+		res = append(res, a)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Load reads the advisories at path, a directory of OSV JSON files, an OSV
+// JSON file or a simple advisory list.
+func Load(path string) ([]Advisory, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	if fi.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+	} else {
+		files = []string{path}
+	}
+	var res []Advisory
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		var advs []Advisory
+		if strings.HasSuffix(file, ".json") {
+			advs, err = ReadOSV(f)
+		} else {
+			advs, err = ReadList(f)
+		}
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		res = append(res, advs...)
+	}
+	return res, nil
+}
@@ -0,0 +1,172 @@
+// Package vuln implements matching packages against offline vulnerability
+// advisories in OSV JSON format or a simple advisory list.
+//
+// Versions are compared with the xbps version semantics of version.Cmp,
+// advisory versions without a revision match all revisions of a version.
+// Packages are matched by their own version, a package that reverts a
+// fixed version is still affected even though xbps orders it as newer.
+package vuln
+
+import (
+	"sort"
+
+	"github.com/Duncaen/go-xbps/pkgdb"
+	"github.com/Duncaen/go-xbps/pkgver"
+	"github.com/Duncaen/go-xbps/repo"
+	"github.com/Duncaen/go-xbps/version"
+)
+
+// Match is a package affected by an advisory
+type Match struct {
+	// PkgVer is the affected package
+	PkgVer string `json:"pkgver"`
+	// ID is the advisory identifier
+	ID string `json:"id"`
+	// Aliases are other identifiers of the advisory
+	Aliases []string `json:"aliases,omitempty"`
+	// Summary is the summary of the advisory
+	Summary string `json:"summary,omitempty"`
+	// Severity is the severity of the advisory
+	Severity string `json:"severity,omitempty"`
+	// Fixed are the versions fixing the vulnerability
+	Fixed []string `json:"fixed,omitempty"`
+	// Available is the newest fixed package in the repositories
+	Available string `json:"available,omitempty"`
+}
+
+// Matcher matches packages against advisories
+type Matcher struct {
+	// Advisories are the advisories
+	Advisories []Advisory
+	// Ecosystem restricts the affected packages to an OSV ecosystem
+	Ecosystem string
+	// Repos are the repositories searched for fixed packages
+	Repos []*repo.Repository
+
+	byName map[string][]ref
+}
+
+type ref struct {
+	adv      *Advisory
+	affected *Affected
+}
+
+// New returns a matcher for the advisories
+func New(advisories []Advisory, repos ...*repo.Repository) *Matcher {
+	return &Matcher{Advisories: advisories, Repos: repos}
+}
+
+func (m *Matcher) index() {
+	if m.byName != nil {
+		return
+	}
+	m.byName = make(map[string][]ref)
+	for i := range m.Advisories {
+		a := &m.Advisories[i]
+		for j := range a.Affected {
+			af := &a.Affected[j]
+			if m.Ecosystem != "" && af.Package.Ecosystem != m.Ecosystem {
+				continue
+			}
+			m.byName[af.Package.Name] = append(m.byName[af.Package.Name], ref{a, af})
+		}
+	}
+}
+
+// Package returns the advisories affecting the package
+func (m *Matcher) Package(pkg *repo.Package) []Match {
+	m.index()
+	pv, err := pkgver.Parse(pkg.PkgVer)
+	if err != nil || pv.Version == "" {
+		return nil
+	}
+	var res []Match
+	for _, r := range m.byName[pv.Name] {
+		if !r.affected.Affects(pv.Version) {
+			continue
+		}
+		res = append(res, Match{
+			PkgVer:    pkg.PkgVer,
+			ID:        r.adv.ID,
+			Aliases:   r.adv.Aliases,
+			Summary:   r.adv.Summary,
+			Severity:  r.adv.Level(r.affected),
+			Fixed:     r.affected.Fixed(),
+			Available: m.available(pv, r.affected),
+		})
+	}
+	return res
+}
+
+// available returns the newest package in the repositories that would
+// update pv and is not affected.
+func (m *Matcher) available(pv pkgver.PkgVer, affected *Affected) string {
+	var best *repo.Package
+	for _, r := range m.Repos {
+		pkg, ok := r.Index[pv.Name]
+		if !ok || !update(&pkg, pv.Version) {
+			continue
+		}
+		cand, err := pkgver.Parse(pkg.PkgVer)
+		if err != nil || affected.Affects(cand.Version) {
+			continue
+		}
+		if best == nil || update(&pkg, versionOf(best)) {
+			best = &pkg
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.PkgVer
+}
+
+// update returns true if xbps would update version v to the package,
+// if the package is newer or reverts v.
+func update(pkg *repo.Package, v string) bool {
+	for _, r := range pkg.Reverts {
+		if r == v {
+			return true
+		}
+	}
+	return version.Cmp(versionOf(pkg), v) > 0
+}
+
+func versionOf(pkg *repo.Package) string {
+	pv, _ := pkgver.Parse(pkg.PkgVer)
+	return pv.Version
+}
+
+// Packages returns the advisories affecting the packages in order
+func (m *Matcher) Packages(pkgs []repo.Package) []Match {
+	var res []Match
+	for i := range pkgs {
+		res = append(res, m.Package(&pkgs[i])...)
+	}
+	return res
+}
+
+// PkgDB returns the advisories affecting the installed packages ordered by
+// package name.
+func (m *Matcher) PkgDB(db *pkgdb.DB) []Match {
+	var pkgs []repo.Package
+	for _, name := range db.Names() {
+		pkgs = append(pkgs, db.Packages[name].Package)
+	}
+	return m.Packages(pkgs)
+}
+
+// Repository returns the advisories affecting the repository index ordered
+// by package name.
+func (m *Matcher) Repository(r *repo.Repository) []Match {
+	names := make([]string, 0, len(r.Index))
+	for name := range r.Index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pkgs []repo.Package
+	for _, name := range names {
+		pkgs = append(pkgs, r.Index[name])
+	}
+	return m.Packages(pkgs)
+}
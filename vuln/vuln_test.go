@@ -0,0 +1,138 @@
+package vuln
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Duncaen/go-xbps/repo"
+)
+
+const osvJSON = `[{
+	"id": "OSV-2024-1",
+	"aliases": ["CVE-2024-0001"],
+	"summary": "foo overflow",
+	"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+	"affected": [{
+		"package": {"ecosystem": "Void", "name": "foo"},
+		"ranges": [{"type": "ECOSYSTEM", "events": [{"fixed": "2.0"}, {"introduced": "0"}]}],
+		"database_specific": {"severity": "HIGH"}
+	}]
+}, {
+	"id": "OSV-2024-2",
+	"affected": [{
+		"package": {"ecosystem": "Void", "name": "bar"},
+		"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "1.2"}, {"fixed": "1.2.1"}]}]
+	}]
+}]`
+
+const list = `# advisories
+CVE-2024-0002 baz<1.0_3 critical patched in revision 3
+CVE-2024-0003 qux>=1.0<1.5_1
+`
+
+func TestAffects(t *testing.T) {
+	advs, err := ReadOSV(strings.NewReader(osvJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		affected *Affected
+		version  string
+		expect   bool
+	}{
+		{&advs[0].Affected[0], "1.9_1", true},
+		{&advs[0].Affected[0], "2.0rc1_1", true},
+		{&advs[0].Affected[0], "2.0alpha_1", true},
+		{&advs[0].Affected[0], "2.0_1", false},
+		{&advs[0].Affected[0], "2.0.1_1", false},
+		{&advs[1].Affected[0], "1.1_1", false},
+		{&advs[1].Affected[0], "1.2_4", true},
+		{&advs[1].Affected[0], "1.2.1_1", false},
+	}
+	for _, tt := range tests {
+		if got := tt.affected.Affects(tt.version); got != tt.expect {
+			t.Errorf("%s %s: expected %v, got %v", tt.affected.Package.Name, tt.version, tt.expect, got)
+		}
+	}
+
+	advs, err = ReadList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(advs) != 2 || advs[0].DatabaseSpecific.Severity != "CRITICAL" || advs[0].Summary != "patched in revision 3" {
+		t.Fatalf("unexpected advisories %+v", advs)
+	}
+	for v, expect := range map[string]bool{"1.0_2": true, "1.0_3": false, "0.9_5": true} {
+		if got := advs[0].Affected[0].Affects(v); got != expect {
+			t.Errorf("baz %s: expected %v, got %v", v, expect, got)
+		}
+	}
+	if fixed := advs[1].Affected[0].Fixed(); !reflect.DeepEqual(fixed, []string{"1.5_1"}) {
+		t.Errorf("unexpected fixed versions %v", fixed)
+	}
+}
+
+func TestAffectsRevision(t *testing.T) {
+	// the same bound as package pattern and as OSV versions or range
+	tests := []struct {
+		pattern string
+		osv     Affected
+		version string
+		expect  bool
+	}{
+		{"foo<1.0", Affected{Ranges: []Range{{Events: []Event{{Introduced: "0"}, {Fixed: "1.0"}}}}}, "1.0_1", false},
+		{"foo<1.0", Affected{Ranges: []Range{{Events: []Event{{Introduced: "0"}, {Fixed: "1.0"}}}}}, "0.9_3", true},
+		{"foo<=1.0", Affected{Ranges: []Range{{Events: []Event{{Introduced: "0"}, {LastAffected: "1.0"}}}}}, "1.0_2", true},
+		{"foo<=1.0", Affected{Ranges: []Range{{Events: []Event{{Introduced: "0"}, {LastAffected: "1.0"}}}}}, "1.0.1_1", false},
+		{"foo>=1.0", Affected{Ranges: []Range{{Events: []Event{{Introduced: "1.0"}}}}}, "1.0_1", true},
+		{"foo>=1.0", Affected{Ranges: []Range{{Events: []Event{{Introduced: "1.0"}}}}}, "0.9_1", false},
+		{"foo>=1.0_2", Affected{Ranges: []Range{{Events: []Event{{Introduced: "1.0_2"}}}}}, "1.0_1", false},
+		{"foo<1.0_3", Affected{Ranges: []Range{{Events: []Event{{Introduced: "0"}, {Fixed: "1.0_3"}}}}}, "1.0_2", true},
+		{"foo<1.0_3", Affected{Ranges: []Range{{Events: []Event{{Introduced: "0"}, {Fixed: "1.0_3"}}}}}, "1.0_3", false},
+		{"foo==1.0", Affected{Versions: []string{"1.0"}}, "1.0_4", true},
+		{"foo==1.0", Affected{Versions: []string{"1.0"}}, "1.0.1_1", false},
+	}
+	for _, tt := range tests {
+		pattern := Affected{Package: Package{Name: "foo"}, Pattern: tt.pattern}
+		tt.osv.Package = Package{Name: "foo"}
+		if got := pattern.Affects(tt.version); got != tt.expect {
+			t.Errorf("%s %s: expected %v, got %v", tt.pattern, tt.version, tt.expect, got)
+		}
+		if got := tt.osv.Affects(tt.version); got != tt.expect {
+			t.Errorf("%+v %s: expected %v, got %v", tt.osv, tt.version, tt.expect, got)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	advs, err := ReadOSV(strings.NewReader(osvJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixes := &repo.Repository{Index: map[string]repo.Package{
+		"foo": {PkgVer: "foo-2.0_1"},
+		// reverts the affected version but is not affected itself
+		"bar": {PkgVer: "bar-1.1_1", Reverts: []string{"1.2_1"}},
+	}}
+	installed := &repo.Repository{Index: map[string]repo.Package{
+		"foo": {PkgVer: "foo-1.0_1"},
+		"bar": {PkgVer: "bar-1.2_1"},
+		"baz": {PkgVer: "baz-1.0_1"},
+	}}
+	got := New(advs, fixes).Repository(installed)
+	expect := []Match{
+		{PkgVer: "bar-1.2_1", ID: "OSV-2024-2", Fixed: []string{"1.2.1"}, Available: "bar-1.1_1"},
+		{PkgVer: "foo-1.0_1", ID: "OSV-2024-1", Aliases: []string{"CVE-2024-0001"}, Summary: "foo overflow",
+			Severity: "HIGH", Fixed: []string{"2.0"}, Available: "foo-2.0_1"},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", expect, got)
+	}
+
+	// a package reverting the fixed version is still affected
+	m := New(advs)
+	if got := m.Package(&repo.Package{PkgVer: "foo-1.9_1", Reverts: []string{"2.0_1"}}); len(got) != 1 {
+		t.Fatalf("expected reverting package to be affected, got %+v", got)
+	}
+}